@@ -5,16 +5,18 @@ import (
 	"errors"
 	"io"
 	"log"
+	"strings"
 
 	"github.com/operator-framework/operator-manifest-tools/internal/utils"
 	"github.com/operator-framework/operator-manifest-tools/pkg/image"
-	"github.com/operator-framework/operator-manifest-tools/pkg/pullspec"
 	"github.com/spf13/cobra"
 )
 
 // extractCmdArgs is the args file type for the extractCmd
 type extractCmdArgs struct {
 	outputFile utils.OutputParam
+	manifests  manifestOptions
+	detailed   bool
 }
 
 var (
@@ -36,7 +38,11 @@ in MANIFEST_DIR.`,
 			return extractCmdData.outputFile.Close()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return extract(args[0], &extractCmdData.outputFile)
+			if extractCmdData.detailed {
+				return extractDetailed(args[0], &extractCmdData.manifests, &extractCmdData.outputFile)
+			}
+
+			return extract(args[0], &extractCmdData.manifests, &extractCmdData.outputFile)
 		},
 	}
 )
@@ -46,16 +52,22 @@ func init() {
 	extractCmdData.outputFile.AddFlag(extractCmd, "output", "-",
 		`The path to store the extracted image references. Use - to
 specify stdout. By default - is used.`)
+	extractCmdData.manifests.addFlags(extractCmd)
+
+	extractCmd.Flags().BoolVar(&extractCmdData.detailed,
+		"detailed", false, strings.ReplaceAll(`When set, every occurrence of an image reference is listed with
+the kind of field, the name, the owning deployment and container and the location (file, path, line and column) it was found in.
+By default this option is not set and a deduplicated list of image references is written.`, "\n", " "))
 }
 
 // extract will extract images from the CSV located on the path
-func extract(manifestPath string, output io.Writer) error {
+func extract(manifestPath string, manifests *manifestOptions, output io.Writer) error {
 	log.Printf("extracting image references from %s\n", manifestPath)
-	operatorManifests, err := pullspec.FromDirectory(manifestPath, pullspec.DefaultHeuristic)
+	bundles, err := manifests.load(manifestPath)
 	if err != nil {
 		return err
 	}
-	imageNames, err := image.Extract(operatorManifests)
+	imageNames, err := image.ExtractBundles(bundles)
 	if err != nil {
 		return err
 	}
@@ -65,3 +77,22 @@ func extract(manifestPath string, output io.Writer) error {
 
 	return nil
 }
+
+// extractDetailed will extract every image reference from the CSV located on
+// the path with its location.
+func extractDetailed(manifestPath string, manifests *manifestOptions, output io.Writer) error {
+	log.Printf("extracting detailed image references from %s\n", manifestPath)
+	bundles, err := manifests.load(manifestPath)
+	if err != nil {
+		return err
+	}
+	references, err := image.ExtractDetailed(bundles)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(output).Encode(references); err != nil {
+		return errors.New("error marshaling json: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package pinning
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+	"github.com/operator-framework/operator-manifest-tools/pkg/pullspec"
+	"github.com/spf13/cobra"
+)
+
+// manifestOptions are the options shared by the commands reading the
+// manifests of MANIFEST_DIR.
+type manifestOptions struct {
+	multipleBundles bool
+	allManifests    bool
+	locatorConfig   string
+	containerArgs   string
+	imageEnvs       string
+	scope           string
+	excludePaths    []string
+	suppress        []string
+	suppressFile    string
+}
+
+// addFlags mounts the manifest options on the command.
+func (opts *manifestOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&opts.multipleBundles,
+		"multiple-bundles", false, strings.ReplaceAll(`When set, every CSV found in MANIFEST_DIR is used. CSVs are grouped
+by the directory holding them and each directory may only hold a single CSV. By default
+this option is not set and MANIFEST_DIR must hold a single CSV.`, "\n", " "))
+
+	cmd.Flags().BoolVar(&opts.allManifests,
+		"all-manifests", false, strings.ReplaceAll(`When set, the image references of every manifest next to the CSV,
+like CRDs, ConfigMaps or Deployments, are used too and added to the CSV relatedImages. By default
+this option is not set and only the CSV is used.`, "\n", " "))
+
+	cmd.Flags().StringVar(&opts.locatorConfig,
+		"locator-config", "", strings.ReplaceAll(`The path to a yaml file configuring where the images of the CSV are.
+It may set the annotationKeys holding images, disable built-in locators by name (relatedImages, containers,
+initContainers, relatedImageEnvs, annotations and guessedAnnotations) and add locators with a name and a
+path like spec.install.spec.deployments[*].spec.template.spec.volumes[*].image.reference. The
+containerArgPattern and imageEnvPattern regular expressions, like image$ and _IMAGE$, enable finding images
+in the container command and args flags and in env vars whose name matches.`, "\n", " "))
+
+	cmd.Flags().StringVar(&opts.containerArgs,
+		"container-arg-pattern", "", strings.ReplaceAll(`A regular expression matching the names of the container command
+and args flags holding images, like image$ for --operand-image=quay.io/team/operand:1. It sets the
+containerArgPattern of the locator config. The value must follow an =, the flag without a value uses
+(?i)image$. By default the flags aren't scanned.`, "\n", " "))
+	cmd.Flags().Lookup("container-arg-pattern").NoOptDefVal = pullspec.DefaultContainerArgPattern.String()
+
+	cmd.Flags().StringVar(&opts.imageEnvs,
+		"image-env-pattern", "", strings.ReplaceAll(`A regular expression matching the names of the container env vars
+holding images, like _IMAGE$. It sets the imageEnvPattern of the locator config. The value must follow
+an =, the flag without a value uses _IMAGE$. By default only the RELATED_IMAGE_ env vars are used.`, "\n", " "))
+	cmd.Flags().Lookup("image-env-pattern").NoOptDefVal = pullspec.DefaultImageEnvPattern.String()
+
+	cmd.Flags().StringVar(&opts.scope,
+		"scope", string(pullspec.ScopeEverywhere), strings.ReplaceAll(fmt.Sprintf(`Which strings of the manifests are
+scanned for images, valid values are %v. known only uses the fields known to hold images, annotations also
+guesses images in annotations and everywhere guesses images in any string, like the CSV description.`, pullspec.Scopes), "\n", " "))
+
+	cmd.Flags().StringArrayVar(&opts.excludePaths,
+		"exclude-path", nil, strings.ReplaceAll(`A path of the manifests where images are ignored, like spec.description
+or spec.install.spec.deployments[*].spec.template.spec.containers[?name=sample]. May be repeated.`, "\n", " "))
+
+	cmd.Flags().StringArrayVar(&opts.suppress,
+		"suppress", nil, strings.ReplaceAll(`A string the heuristic must not report as an image, like
+docs.example.com/guide:v1. It may be a pattern like github.com/example/*, or a regular expression prefixed
+with re:. May be repeated.`, "\n", " "))
+
+	cmd.Flags().StringVar(&opts.suppressFile,
+		"suppress-file", "", strings.ReplaceAll(`The path to a file listing the strings the heuristic must not report
+as images, one per line, in the format of --suppress. Empty lines and lines starting with # are ignored.`, "\n", " "))
+}
+
+// load reads the bundles found in the manifest directory.
+func (opts *manifestOptions) load(manifestDir string) ([]*pullspec.Bundle, error) {
+	bundleOpts := []pullspec.BundleOption{}
+
+	if opts.allManifests {
+		bundleOpts = append(bundleOpts, pullspec.WithAllManifests())
+	}
+
+	scanConfig := &pullspec.ScanConfig{ExcludePaths: opts.excludePaths, Suppress: opts.suppress}
+
+	if opts.suppressFile != "" {
+		entries, err := pullspec.LoadSuppressions(opts.suppressFile)
+		if err != nil {
+			return nil, err
+		}
+
+		scanConfig.Suppress = append(entries, scanConfig.Suppress...)
+	}
+
+	if opts.scope != "" {
+		scope, err := pullspec.ParseScope(opts.scope)
+		if err != nil {
+			return nil, err
+		}
+
+		scanConfig.Scope = scope
+	}
+
+	bundleOpts = append(bundleOpts, pullspec.WithScanConfig(scanConfig))
+
+	config := &pullspec.LocatorConfig{}
+
+	if opts.locatorConfig != "" {
+		var err error
+		config, err = pullspec.LoadLocatorConfig(opts.locatorConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.containerArgs != "" {
+		config.ContainerArgPattern = opts.containerArgs
+	}
+
+	if opts.imageEnvs != "" {
+		config.ImageEnvPattern = opts.imageEnvs
+	}
+
+	if opts.locatorConfig != "" || opts.containerArgs != "" || opts.imageEnvs != "" {
+		bundleOpts = append(bundleOpts, pullspec.WithLocatorConfig(config))
+	}
+
+	bundles, err := pullspec.BundlesFromDirectory(manifestDir, pullspec.DefaultHeuristic, bundleOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.multipleBundles && len(bundles) > 1 {
+		log.Printf("found too many csvs in the directory")
+		return nil, utils.ErrTooManyCSVs
+	}
+
+	log.Printf("found %d bundles in %s\n", len(bundles), manifestDir)
+	return bundles, nil
+}
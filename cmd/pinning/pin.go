@@ -25,6 +25,8 @@ type pinCmdArgs struct {
 	resolverArgs map[string]string
 	authFile     string
 	dryRun       bool
+	manifests    manifestOptions
+	replace      replaceOptions
 
 	outputExtract utils.OutputParam
 	outputReplace utils.OutputParam
@@ -79,6 +81,8 @@ the resolved, pinned, version.`,
 
 			return pin(
 				manifestDir,
+				&pinCmdData.manifests,
+				&pinCmdData.replace,
 				resolver,
 				pinCmdData.outputExtract,
 				pinCmdData.outputReplace,
@@ -89,16 +93,22 @@ the resolved, pinned, version.`,
 
 func pin(
 	manifestDir string,
+	manifests *manifestOptions,
+	opts *replaceOptions,
 	resolver imageresolver.ImageResolver,
 	outputExtract, outputReplace utils.OutputParam,
 ) error {
 	defer outputExtract.Close()
 	defer outputReplace.Close()
 
+	if _, err := opts.relatedImagesOptions(); err != nil {
+		return err
+	}
+
 	if err := outputExtract.FromFile(); err != nil {
 		return errors.New("error extracting: " + err.Error())
 	}
-	if err := extract(manifestDir, &outputExtract); err != nil {
+	if err := extract(manifestDir, manifests, &outputExtract); err != nil {
 		return errors.New("error extracting: " + err.Error())
 	}
 
@@ -110,7 +120,7 @@ func pin(
 	if err := outputReplace.FromFile(); err != nil {
 		return errors.New("failure to setup replace output: " + err.Error())
 	}
-	if err = resolve(resolver, inputExtract, &outputReplace); err != nil {
+	if err = resolve(resolver, inputExtract, &outputReplace, opts.keepTag); err != nil {
 		return errors.New("error resolving: " + err.Error())
 	}
 
@@ -119,7 +129,7 @@ func pin(
 		return errors.New("failure reading replace data: " + err.Error())
 	}
 	defer inputReplace.Close()
-	if err = replace(manifestDir, inputReplace); err != nil {
+	if err = replace(manifestDir, manifests, opts, inputReplace); err != nil {
 		return errors.New("error replacing: " + err.Error())
 	}
 
@@ -150,5 +160,8 @@ By default this option is not set.`, "\n", " "))
 	pinCmd.Flags().StringVarP(&pinCmdData.authFile,
 		"authfile", "a", "", "The path to the authentication file for registry communication.")
 
+	pinCmdData.manifests.addFlags(pinCmd)
+	pinCmdData.replace.addFlags(pinCmd)
+
 	mountResolverOpts(pinCmd, &pinCmdData.resolver, &pinCmdData.resolverArgs)
 }
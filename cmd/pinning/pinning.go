@@ -13,4 +13,5 @@ func init() {
 	PinningCmd.AddCommand(replaceCmd)
 	PinningCmd.AddCommand(extractCmd)
 	PinningCmd.AddCommand(resolveCmd)
+	PinningCmd.AddCommand(rewriteCmd)
 }
@@ -43,12 +43,12 @@ var _ = Describe("pinning", func() {
 
 		ioutil.WriteFile(resolverScript, []byte(`#!/bin/bash
 if [ "$1" == "registry.example.com/eggs:9.8" ]; then
-   echo -n "2"
+   echo -n "2222222222222222222222222222222222222222222222222222222222222222"
    exit 0
 fi
 
 if [ "$1" == "registry.example.com/maps/spam-operator:1.2" ]; then
-   echo -n "1"
+   echo -n "1111111111111111111111111111111111111111111111111111111111111111"
    exit 0
 fi
 
@@ -67,7 +67,7 @@ exit 1
 	Context("extract", func() {
 		BeforeEach(func() {
 			eggsImageReference = "registry.example.com/eggs:9.8"
-			spamImageReference = "registry.example.com/maps/spam-operator@sha256:1"
+			spamImageReference = "registry.example.com/maps/spam-operator@sha256:1111111111111111111111111111111111111111111111111111111111111111"
 
 			csvFile, err := os.OpenFile(csvFilePath, os.O_CREATE|os.O_RDWR, 0755)
 			defer csvFile.Close()
@@ -87,7 +87,7 @@ exit 1
 
 		It("should perform extract from csv", func() {
 			extractData := bytes.Buffer{}
-			extract(manifestDir, &extractData)
+			extract(manifestDir, &manifestOptions{}, &extractData)
 
 			extractJson := []interface{}{}
 
@@ -95,6 +95,29 @@ exit 1
 			Expect(extractJson).To(HaveLen(2))
 			Expect(extractJson).To(ConsistOf(eggsImageReference, spamImageReference))
 		})
+
+		It("should perform a detailed extract from csv", func() {
+			extractData := bytes.Buffer{}
+			Expect(extractDetailed(manifestDir, &manifestOptions{}, &extractData)).To(Succeed())
+
+			extractJson := []map[string]interface{}{}
+
+			Expect(json.Unmarshal(extractData.Bytes(), &extractJson)).To(Succeed())
+			Expect(extractJson).To(ContainElement(map[string]interface{}{
+				"image": eggsImageReference,
+				"kind":  "container",
+				"name":  "eggs",
+				"owner": map[string]interface{}{
+					"container": "eggs",
+				},
+				"location": map[string]interface{}{
+					"file":   csvFilePath,
+					"path":   "spec.install.spec.deployments[0].spec.template.spec.containers[1].image",
+					"line":   float64(16),
+					"column": float64(24),
+				},
+			}))
+		})
 	})
 
 	Context("resolve", func() {
@@ -126,7 +149,7 @@ exit 1
 
 		It("should resolve image references", func() {
 			resolveData := bytes.Buffer{}
-			err := resolve(resolver, bytes.NewReader(extractData), &resolveData)
+			err := resolve(resolver, bytes.NewReader(extractData), &resolveData, false)
 			Expect(err).To(Succeed())
 
 			resolveJson := map[string]interface{}{}
@@ -134,8 +157,22 @@ exit 1
 			Expect(resolveJson).To(HaveLen(2))
 			Expect(resolveJson).To(Equal(
 				map[string]interface{}{
-					"registry.example.com/eggs:9.8":               "registry.example.com/eggs@sha256:2",
-					"registry.example.com/maps/spam-operator:1.2": "registry.example.com/maps/spam-operator@sha256:1",
+					"registry.example.com/eggs:9.8":               "registry.example.com/eggs@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+					"registry.example.com/maps/spam-operator:1.2": "registry.example.com/maps/spam-operator@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+				}))
+		})
+
+		It("should keep the tags next to the digests", func() {
+			resolveData := bytes.Buffer{}
+			err := resolve(resolver, bytes.NewReader(extractData), &resolveData, true)
+			Expect(err).To(Succeed())
+
+			resolveJson := map[string]interface{}{}
+			Expect(json.Unmarshal(resolveData.Bytes(), &resolveJson)).To(Succeed())
+			Expect(resolveJson).To(Equal(
+				map[string]interface{}{
+					"registry.example.com/eggs:9.8":               "registry.example.com/eggs:9.8@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+					"registry.example.com/maps/spam-operator:1.2": "registry.example.com/maps/spam-operator:1.2@sha256:1111111111111111111111111111111111111111111111111111111111111111",
 				}))
 		})
 	})
@@ -170,21 +207,31 @@ exit 1
 					Vars map[string]string
 				}{
 					map[string]string{
-						"Eggs": "registry.example.com/eggs@sha256:2",
-						"Spam": "registry.example.com/maps/spam-operator@sha256:1",
+						"Eggs": "registry.example.com/eggs@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+						"Spam": "registry.example.com/maps/spam-operator@sha256:1111111111111111111111111111111111111111111111111111111111111111",
 					},
 				})
 
 			resolvedFile = resolvedFileBuffer.Bytes()
 
 			resolveData, _ = json.Marshal(map[string]interface{}{
-				"registry.example.com/eggs:9.8":               "registry.example.com/eggs@sha256:2",
-				"registry.example.com/maps/spam-operator:1.2": "registry.example.com/maps/spam-operator@sha256:1",
+				"registry.example.com/eggs:9.8":               "registry.example.com/eggs@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+				"registry.example.com/maps/spam-operator:1.2": "registry.example.com/maps/spam-operator@sha256:1111111111111111111111111111111111111111111111111111111111111111",
 			})
 		})
 
 		It("should replace image refs", func() {
-			err := replace(manifestDir, bytes.NewReader(resolveData))
+			err := replace(manifestDir, &manifestOptions{}, &replaceOptions{relatedImagesPolicy: "preserve", relatedImagesConflicts: "fail"}, bytes.NewReader(resolveData))
+			Expect(err).To(Succeed())
+
+			fileData, err := ioutil.ReadFile(csvFilePath)
+			Expect(err).To(Succeed())
+
+			Expect(fileData).To(MatchUnorderedYAML(resolvedFile))
+		})
+
+		It("should keep fully-qualified image refs when normalizing", func() {
+			err := replace(manifestDir, &manifestOptions{}, &replaceOptions{relatedImagesPolicy: "preserve", relatedImagesConflicts: "fail", normalize: true}, bytes.NewReader(resolveData))
 			Expect(err).To(Succeed())
 
 			fileData, err := ioutil.ReadFile(csvFilePath)
@@ -194,6 +241,68 @@ exit 1
 		})
 	})
 
+	Context("rewrite", func() {
+		var (
+			rules         = rewriteRules(map[string]string{"registry.example.com": "registry.prod.example.com"}, map[string]string{"registry.example.com": "partner-org"})
+			rewrittenFile []byte
+		)
+
+		BeforeEach(func() {
+			csvFile, err := os.OpenFile(csvFilePath, os.O_CREATE|os.O_WRONLY, 0755)
+			defer csvFile.Close()
+			Expect(err).To(Succeed())
+
+			csvOriginal.Execute(csvFile,
+				struct {
+					Vars map[string]string
+				}{
+					map[string]string{
+						"Eggs": "registry.example.com/eggs:9.8",
+						"Spam": "registry.example.com/maps/spam-operator:1.2",
+					},
+				})
+
+			var rewrittenFileBuffer bytes.Buffer
+			resolved.Execute(&rewrittenFileBuffer,
+				struct {
+					Vars map[string]string
+				}{
+					map[string]string{
+						"Eggs": "registry.prod.example.com/partner-org/eggs:9.8",
+						"Spam": "registry.prod.example.com/partner-org/maps-spam-operator:1.2",
+					},
+				})
+
+			rewrittenFile = rewrittenFileBuffer.Bytes()
+		})
+
+		It("should write the replacements", func() {
+			var output bytes.Buffer
+			err := rewrite(manifestDir, &manifestOptions{}, rules, &output)
+			Expect(err).To(Succeed())
+
+			Expect(output.String()).To(MatchJSON(`{
+				"registry.example.com/eggs:9.8": "registry.prod.example.com/partner-org/eggs:9.8",
+				"registry.example.com/maps/spam-operator:1.2": "registry.prod.example.com/partner-org/maps-spam-operator:1.2"
+			}`))
+		})
+
+		It("should rewrite image refs in place", func() {
+			err := rewriteInPlace(manifestDir, &manifestOptions{}, &replaceOptions{relatedImagesPolicy: "preserve", relatedImagesConflicts: "fail"}, rules, false)
+			Expect(err).To(Succeed())
+
+			fileData, err := ioutil.ReadFile(csvFilePath)
+			Expect(err).To(Succeed())
+
+			Expect(fileData).To(MatchUnorderedYAML(rewrittenFile))
+		})
+
+		It("should require a rule", func() {
+			err := rewrite(manifestDir, &manifestOptions{}, nil, &bytes.Buffer{})
+			Expect(err).To(MatchError(ContainSubstring("no rewrite rule")))
+		})
+	})
+
 	Context("pin", func() {
 		var (
 			outputExtract, outputReplace utils.OutputParam
@@ -236,8 +345,8 @@ exit 1
 					Vars map[string]string
 				}{
 					map[string]string{
-						"Eggs": "registry.example.com/eggs@sha256:2",
-						"Spam": "registry.example.com/maps/spam-operator@sha256:1",
+						"Eggs": "registry.example.com/eggs@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+						"Spam": "registry.example.com/maps/spam-operator@sha256:1111111111111111111111111111111111111111111111111111111111111111",
 					},
 				})
 
@@ -252,6 +361,8 @@ exit 1
 		It("should replace image refs", func() {
 			err := pin(
 				manifestDir,
+				&manifestOptions{},
+				&replaceOptions{relatedImagesPolicy: "preserve", relatedImagesConflicts: "fail"},
 				resolver,
 				outputExtract,
 				outputReplace,
@@ -276,8 +387,8 @@ exit 1
 			Expect(resolveJson).To(HaveLen(2))
 			Expect(resolveJson).To(Equal(
 				map[string]interface{}{
-					"registry.example.com/eggs:9.8":               "registry.example.com/eggs@sha256:2",
-					"registry.example.com/maps/spam-operator:1.2": "registry.example.com/maps/spam-operator@sha256:1",
+					"registry.example.com/eggs:9.8":               "registry.example.com/eggs@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+					"registry.example.com/maps/spam-operator:1.2": "registry.example.com/maps/spam-operator@sha256:1111111111111111111111111111111111111111111111111111111111111111",
 				}))
 
 			replaceAnswer, err := os.ReadFile(csvFilePath)
@@ -289,6 +400,112 @@ exit 1
 		})
 	})
 
+	Context("multiple bundles", func() {
+		var (
+			outputExtract, outputReplace utils.OutputParam
+
+			resolvedFile             []byte
+			bundleCSVs               []string
+			extractFile, replaceFile *os.File
+		)
+
+		BeforeEach(func() {
+			eggsImageReference = "registry.example.com/eggs:9.8"
+			spamImageReference = "registry.example.com/maps/spam-operator:1.2"
+			bundleCSVs = []string{
+				filepath.Join(manifestDir, "1.0.0", "clusterserviceversion.yaml"),
+				filepath.Join(manifestDir, "1.1.0", "clusterserviceversion.yaml"),
+			}
+
+			for _, path := range bundleCSVs {
+				Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+
+				csvFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0755)
+				Expect(err).To(Succeed())
+
+				csvOriginal.Execute(csvFile,
+					struct {
+						Vars map[string]string
+					}{
+						map[string]string{
+							"Eggs": eggsImageReference,
+							"Spam": spamImageReference,
+						},
+					})
+				csvFile.Close()
+			}
+
+			var err error
+			extractFile, err = ioutil.TempFile(dir, "extract")
+			Expect(err).To(Succeed())
+			outputExtract = utils.NewOutputParam()
+			outputExtract.Name = extractFile.Name()
+
+			replaceFile, err = ioutil.TempFile(dir, "replace")
+			Expect(err).To(Succeed())
+			outputReplace = utils.NewOutputParam()
+			outputReplace.Name = replaceFile.Name()
+
+			var resolvedFileBuffer bytes.Buffer
+			resolved.Execute(&resolvedFileBuffer,
+				struct {
+					Vars map[string]string
+				}{
+					map[string]string{
+						"Eggs": "registry.example.com/eggs@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+						"Spam": "registry.example.com/maps/spam-operator@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+					},
+				})
+
+			resolvedFile = resolvedFileBuffer.Bytes()
+		})
+
+		AfterEach(func() {
+			os.Remove(extractFile.Name())
+			os.Remove(replaceFile.Name())
+		})
+
+		It("should refuse multiple CSVs by default", func() {
+			extractData := bytes.Buffer{}
+			Expect(extract(manifestDir, &manifestOptions{}, &extractData)).To(MatchError(utils.ErrTooManyCSVs))
+		})
+
+		It("should extract each image once", func() {
+			extractData := bytes.Buffer{}
+			Expect(extract(manifestDir, &manifestOptions{multipleBundles: true}, &extractData)).To(Succeed())
+
+			extractJson := []interface{}{}
+			Expect(json.Unmarshal(extractData.Bytes(), &extractJson)).To(Succeed())
+			Expect(extractJson).To(HaveLen(2))
+			Expect(extractJson).To(ConsistOf(eggsImageReference, spamImageReference))
+		})
+
+		It("should pin every bundle", func() {
+			err := pin(
+				manifestDir,
+				&manifestOptions{multipleBundles: true},
+				&replaceOptions{relatedImagesPolicy: "preserve", relatedImagesConflicts: "fail"},
+				resolver,
+				outputExtract,
+				outputReplace,
+			)
+			Expect(err).To(Succeed())
+
+			resolveAnswer, err := os.ReadFile(outputReplace.Name)
+			Expect(err).To(Succeed())
+
+			resolveJson := map[string]interface{}{}
+			Expect(json.Unmarshal(resolveAnswer, &resolveJson)).To(Succeed())
+			Expect(resolveJson).To(HaveLen(2))
+
+			for _, path := range bundleCSVs {
+				replaceAnswer, err := os.ReadFile(path)
+				Expect(err).To(Succeed())
+				Expect(replaceAnswer).To(MatchUnorderedYAML(resolvedFile))
+			}
+		})
+	})
+
 })
 
 const CSV_TEMPLATE = `apiVersion: operators.coreos.com/v1alpha1
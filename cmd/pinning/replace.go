@@ -3,9 +3,11 @@ package pinning
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"strings"
+	"text/template"
 
 	"github.com/operator-framework/operator-manifest-tools/internal/utils"
 	"github.com/operator-framework/operator-manifest-tools/pkg/image"
@@ -16,6 +18,18 @@ import (
 type replaceCmdArgs struct {
 	replacementFile utils.InputParam
 	dryRun          bool
+	manifests       manifestOptions
+	replace         replaceOptions
+}
+
+// replaceOptions are the options shared by the commands replacing image
+// references.
+type replaceOptions struct {
+	relatedImagesPolicy       string
+	relatedImagesNameTemplate string
+	relatedImagesConflicts    string
+	normalize                 bool
+	keepTag                   bool
 }
 
 var (
@@ -47,7 +61,7 @@ var replaceCmd = &cobra.Command{
 
 		manifestDir := args[0]
 
-		return replace(manifestDir, &replaceCmdData.replacementFile)
+		return replace(manifestDir, &replaceCmdData.manifests, &replaceCmdData.replace, &replaceCmdData.replacementFile)
 	},
 }
 
@@ -62,38 +76,118 @@ func init() {
 	replaceCmd.Flags().BoolVar(&replaceCmdData.dryRun,
 		"dry-run", false, strings.ReplaceAll(`When set, replacements are not performed. This is useful to determine if the CSV is
 in a state that accepts replacements. By default this option is not set.`, "\n", " "))
+	replaceCmdData.manifests.addFlags(replaceCmd)
+	replaceCmdData.replace.addFlags(replaceCmd)
 
 }
 
+// addFlags mounts the replace options on the command.
+func (opts *replaceOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&opts.relatedImagesPolicy,
+		"related-images-policy", string(pullspec.RelatedImagesPreserve), strings.ReplaceAll(fmt.Sprintf(`What to do with the
+entries already in the CSV relatedImages, valid values are %v. preserve keeps them, prune drops the ones
+whose image isn't referenced anymore and replace rebuilds the relatedImages from scratch.`, pullspec.RelatedImagesPolicies), "\n", " "))
+	cmd.Flags().StringVar(&opts.relatedImagesNameTemplate,
+		"related-images-name-template", "", strings.ReplaceAll(`A go template naming the new relatedImages
+entries, like {{.Container}} or {{.Repo}}-{{.Kind}}. The fields are Name, Kind, Deployment, Container,
+Registry, Namespace, Repo and Tag. By default the entries are named after the field the image was found
+in. The names are made valid DNS-1123 labels.`, "\n", " "))
+	cmd.Flags().StringVar(&opts.relatedImagesConflicts,
+		"related-images-conflicts", pullspec.ConflictFail.String(), strings.ReplaceAll(`What to do when relatedImages
+entries share a name but not an image. fail stops with an error, suffix appends -2, -3, ... to the names
+of the other entries and prefer=<kind> keeps the image found in a field of that kind, like prefer=container.`, "\n", " "))
+	cmd.Flags().BoolVar(&opts.normalize,
+		"normalize", false, strings.ReplaceAll(`When set, the image references are written fully-qualified, like
+docker.io/library/nginx:latest for nginx, the replaced ones included. By default this option is not set.`, "\n", " "))
+	cmd.Flags().BoolVar(&opts.keepTag, "keep-tag", false, keepTagUsage)
+}
+
+// relatedImagesOptions returns the options used to set the relatedImages.
+func (opts *replaceOptions) relatedImagesOptions() ([]pullspec.RelatedImagesOption, error) {
+	policy, err := pullspec.ParseRelatedImagesPolicy(opts.relatedImagesPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts, err := pullspec.ParseConflictStrategy(opts.relatedImagesConflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	relatedImagesOpts := []pullspec.RelatedImagesOption{
+		pullspec.WithRelatedImagesPolicy(policy),
+		pullspec.WithConflictStrategy(conflicts),
+	}
+
+	if opts.relatedImagesNameTemplate != "" {
+		nameTemplate, err := template.New("name").Option("missingkey=error").Parse(opts.relatedImagesNameTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid relatedImages name template: %w", err)
+		}
+
+		relatedImagesOpts = append(relatedImagesOpts, pullspec.WithRelatedImagesNameTemplate(nameTemplate))
+	}
+
+	return relatedImagesOpts, nil
+}
+
 // replace will read manifests from the directory and replace the images from
 // the replacements directory.
-func replace(manifestDir string, replacementsReader io.Reader) error {
+func replace(manifestDir string, manifests *manifestOptions, opts *replaceOptions, replacementsReader io.Reader) error {
+	relatedImagesOpts, err := opts.relatedImagesOptions()
+	if err != nil {
+		return err
+	}
+
 	replacements, err := readReplacements(replacementsReader)
 	if err != nil {
 		return err
 	}
 
-	operatorManifests, err := pullspec.FromDirectory(manifestDir, pullspec.DefaultHeuristic)
+	bundles, err := manifests.load(manifestDir)
 	if err != nil {
 		return err
 	}
-	if err := image.Replace(operatorManifests, replacements); err != nil {
+
+	return opts.replaceBundles(bundles, replacements, relatedImagesOpts, replaceCmdData.dryRun)
+}
+
+// replaceBundles will replace the images of the bundles and update their
+// manifests unless dryRun is set.
+func (opts *replaceOptions) replaceBundles(
+	bundles []*pullspec.Bundle,
+	replacements image.Replacements,
+	relatedImagesOpts []pullspec.RelatedImagesOption,
+	dryRun bool,
+) error {
+	if opts.keepTag {
+		replacements = replacements.KeepTags()
+	}
+
+	if opts.normalize {
+		if err := image.NormalizeBundles(bundles); err != nil {
+			return err
+		}
+
+		replacements = replacements.Normalized()
+	}
+
+	if err := image.ReplaceBundles(bundles, replacements, relatedImagesOpts...); err != nil {
 		return err
 	}
 
-	if replaceCmdData.dryRun {
+	if dryRun {
 		log.Println("dryRun is enabled, no output was generated")
 		return nil
 	}
 
-	for _, manifest := range operatorManifests {
-		if err := manifest.Dump(nil); err != nil {
+	for _, bundle := range bundles {
+		if err := bundle.Dump(); err != nil {
 			return errors.New("failed to update the manifests: " + err.Error())
 		}
 	}
 
 	return nil
-
 }
 
 func readReplacements(r io.Reader) (image.Replacements, error) {
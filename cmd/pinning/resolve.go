@@ -19,6 +19,7 @@ type resolveCmdArgs struct {
 	resolver     string
 	resolverArgs map[string]string
 	authFile     string
+	keepTag      bool
 
 	input      utils.InputParam
 	outputFile utils.OutputParam
@@ -80,6 +81,7 @@ var resolveCmd = &cobra.Command{
 			resolver,
 			&resolveCmdData.input,
 			&resolveCmdData.outputFile,
+			resolveCmdData.keepTag,
 		)
 	},
 }
@@ -92,9 +94,16 @@ func init() {
 		"authfile", "a", "", `The path to the authentication file for registry
 communication using skopeo. Uses skopeo's default if not provided.`)
 
+	resolveCmd.Flags().BoolVar(&resolveCmdData.keepTag,
+		"keep-tag", false, keepTagUsage)
+
 	mountResolverOpts(resolveCmd, &resolveCmdData.resolver, &resolveCmdData.resolverArgs)
 }
 
+// keepTagUsage is the usage of the --keep-tag flags.
+var keepTagUsage = strings.ReplaceAll(`When set, the tag is kept next to the digest in the pinned
+image references, like quay.io/org/op:v1.4.2@sha256:... By default only the digest is used.`, "\n", " ")
+
 var runSkopeoLocationCmd sync.Once
 var skopeoLocation = ""
 
@@ -130,11 +139,13 @@ func mountResolverOpts(
 }
 
 // resolve will read images from the extracted json and write the resolved
-// image to the output using skopeo to look up the image shas.
+// image to the output using skopeo to look up the image shas. With keepTag,
+// the resolved images keep the tag next to the digest.
 func resolve(
 	resolver imageresolver.ImageResolver,
 	input io.Reader,
 	output io.Writer,
+	keepTag bool,
 ) error {
 	references := []string{}
 	if err := json.NewDecoder(input).Decode(&references); err != nil {
@@ -144,6 +155,9 @@ func resolve(
 	if err != nil {
 		return err
 	}
+	if keepTag {
+		replacements = replacements.KeepTags()
+	}
 	if err := json.NewEncoder(output).Encode(replacements); err != nil {
 		return errors.New("error writing files: " + err.Error())
 	}
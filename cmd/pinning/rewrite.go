@@ -0,0 +1,187 @@
+package pinning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+	"github.com/operator-framework/operator-manifest-tools/pkg/image"
+	"github.com/operator-framework/operator-manifest-tools/pkg/pullspec"
+	"github.com/spf13/cobra"
+)
+
+// rewriteCmdArgs is the arguments for the rewrite command
+type rewriteCmdArgs struct {
+	registries map[string]string
+	enclose    map[string]string
+	inPlace    bool
+	dryRun     bool
+	manifests  manifestOptions
+	replace    replaceOptions
+
+	outputFile utils.OutputParam
+}
+
+var (
+	// rewriteCmdData stores the data for rewriteCmd
+	rewriteCmdData = rewriteCmdArgs{
+		outputFile: utils.NewOutputParam(),
+	}
+
+	// rewriteCmd represents the rewrite command
+	rewriteCmd = &cobra.Command{
+		Use:   "rewrite [flags] MANIFEST_DIR",
+		Short: "Rewrite the registry and the organization of the image references from the CSVs found in MANIFEST_DIR.",
+		Long: `Rewrite the registry and the organization of the image references from the CSVs found in
+MANIFEST_DIR, per source registry. For instance registry.stage.example.com/team/op@sha256:... becomes
+registry.example.com/partner-org/team-op@sha256:... with --replace-registry
+registry.stage.example.com=registry.example.com and --enclose registry.stage.example.com=partner-org.
+The replacements are written to the output, to be used with replace, or applied in place with --in-place.`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := utils.CheckIfDirectoryExists(args[0]); err != nil {
+				return err
+			}
+
+			if rewriteCmdData.inPlace {
+				return nil
+			}
+
+			return rewriteCmdData.outputFile.Init(cmd, args)
+		},
+		PostRunE: func(cmd *cobra.Command, args []string) error {
+			if rewriteCmdData.inPlace {
+				return nil
+			}
+
+			return rewriteCmdData.outputFile.Close()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rewriteCmdData.dryRun {
+				log.SetOutput(cmd.ErrOrStderr())
+			}
+
+			rules := rewriteRules(rewriteCmdData.registries, rewriteCmdData.enclose)
+
+			if !rewriteCmdData.inPlace {
+				return rewrite(args[0], &rewriteCmdData.manifests, rules, &rewriteCmdData.outputFile)
+			}
+
+			return rewriteInPlace(args[0], &rewriteCmdData.manifests, &rewriteCmdData.replace, rules, rewriteCmdData.dryRun)
+		},
+	}
+)
+
+func init() {
+	rewriteCmdData.outputFile.AddFlag(rewriteCmd, "output", "-",
+		`The path to store the replacements. Use - to specify stdout. By default - is used.`)
+
+	rewriteCmd.Flags().StringToStringVar(&rewriteCmdData.registries,
+		"replace-registry", nil, strings.ReplaceAll(`The registries to move the images to, per source
+registry, like registry.stage.example.com=registry.example.com.`, "\n", " "))
+	rewriteCmd.Flags().StringToStringVar(&rewriteCmdData.enclose,
+		"enclose", nil, strings.ReplaceAll(`The organizations to enclose the images in, per source registry,
+like registry.stage.example.com=partner-org. The namespace of the images is collapsed into the repository,
+team/op becomes partner-org/team-op.`, "\n", " "))
+	rewriteCmd.Flags().BoolVar(&rewriteCmdData.inPlace,
+		"in-place", false, strings.ReplaceAll(`When set, the image references are rewritten in the manifests
+instead of writing the replacements to the output. By default this option is not set.`, "\n", " "))
+	rewriteCmd.Flags().BoolVar(&rewriteCmdData.dryRun,
+		"dry-run", false, strings.ReplaceAll(`When set with --in-place, the manifests are not updated. By
+default this option is not set.`, "\n", " "))
+
+	rewriteCmdData.manifests.addFlags(rewriteCmd)
+	rewriteCmdData.replace.addFlags(rewriteCmd)
+}
+
+// rewriteRules builds the rules from the target registries and the
+// organizations per source registry. The rules are sorted by source registry.
+func rewriteRules(registries, enclose map[string]string) image.RewriteRules {
+	sources := map[string]bool{}
+	for registry := range registries {
+		sources[registry] = true
+	}
+
+	for registry := range enclose {
+		sources[registry] = true
+	}
+
+	rules := make(image.RewriteRules, 0, len(sources))
+	for registry := range sources {
+		rules = append(rules, image.RewriteRule{
+			Registry: registry,
+			Target:   registries[registry],
+			Enclose:  enclose[registry],
+		})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Registry < rules[j].Registry
+	})
+
+	return rules
+}
+
+// rewriteReplacements returns the replacements rewriting the images of the bundles.
+func rewriteReplacements(bundles []*pullspec.Bundle, rules image.RewriteRules) (image.Replacements, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no rewrite rule, use --replace-registry or --enclose")
+	}
+
+	references, err := image.ExtractBundles(bundles)
+	if err != nil {
+		return nil, err
+	}
+
+	return image.Rewrite(references, rules)
+}
+
+// rewrite will write the replacements rewriting the images of the manifests
+// to the output.
+func rewrite(manifestDir string, manifests *manifestOptions, rules image.RewriteRules, output io.Writer) error {
+	log.Printf("rewriting image references from %s\n", manifestDir)
+	bundles, err := manifests.load(manifestDir)
+	if err != nil {
+		return err
+	}
+
+	replacements, err := rewriteReplacements(bundles, rules)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(output).Encode(replacements); err != nil {
+		return errors.New("error marshaling json: " + err.Error())
+	}
+
+	return nil
+}
+
+// rewriteInPlace will rewrite the images of the manifests.
+func rewriteInPlace(manifestDir string, manifests *manifestOptions, opts *replaceOptions, rules image.RewriteRules, dryRun bool) error {
+	relatedImagesOpts, err := opts.relatedImagesOptions()
+	if err != nil {
+		return err
+	}
+
+	bundles, err := manifests.load(manifestDir)
+	if err != nil {
+		return err
+	}
+
+	replacements, err := rewriteReplacements(bundles, rules)
+	if err != nil {
+		return err
+	}
+
+	if err := opts.replaceBundles(bundles, replacements, relatedImagesOpts, dryRun); err != nil {
+		return fmt.Errorf("error rewriting: %w", err)
+	}
+
+	return nil
+}
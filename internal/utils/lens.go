@@ -1,123 +1,238 @@
 package utils
 
 import (
+	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+// lensStep is a single step of a lens.
+type lensStep interface {
+	// segment formats the step as a path segment, like .key, [0] or [*].
+	segment() string
+}
+
+// keyStep navigates a map by a key.
+type keyStep struct {
+	key string
+}
+
+// indexStep navigates a slice by an index.
+type indexStep struct {
+	i int
+}
+
+// applyStep loops over a slice and applies a lens to each element that
+// matches the filter, if any.
+type applyStep struct {
+	lens   lens
+	filter *filter
+}
+
+// filter matches the maps whose key holds value.
+type filter struct {
+	key, value string
+}
+
+var plainKey = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_\-]*$`)
+
+func (s keyStep) segment() string {
+	if !plainKey.MatchString(s.key) {
+		return fmt.Sprintf("[%s]", strconv.Quote(s.key))
+	}
+
+	return "." + s.key
+}
+
+func (s indexStep) segment() string {
+	return fmt.Sprintf("[%d]", s.i)
+}
+
+func (s applyStep) segment() string {
+	if s.filter != nil {
+		value := s.filter.value
+
+		if !plainKey.MatchString(value) {
+			value = strconv.Quote(value)
+		}
+
+		return fmt.Sprintf("[?%s=%s]", s.filter.key, value) + joinSegments(s.lens.steps)
+	}
+
+	return "[*]" + joinSegments(s.lens.steps)
+}
+
+// matches returns true if the filter matches data.
+func (f *filter) matches(data interface{}) bool {
+	if f == nil {
+		return true
+	}
+
+	mmap, ok := data.(map[string]interface{})
+
+	if !ok {
+		return false
+	}
+
+	v, ok := mmap[f.key]
+	return ok && fmt.Sprintf("%v", v) == f.value
+}
+
 // lensBuilder is used to construct lens
 type lensBuilder struct {
-	funcs []func(interface{}) (interface{}, error)
-	path  []string
+	steps []lensStep
 }
 
-// lens holds a series of functions that helps navigate a map[string]interface{} data structure
+// lens holds a series of steps that helps navigate a map[string]interface{} data structure
 type lens struct {
-	funcs []func(interface{}) (interface{}, error)
+	steps []lensStep
 }
 
 // newLens creates a new lens builder
 func Lens() *lensBuilder {
 	return &lensBuilder{
-		funcs: []func(interface{}) (interface{}, error){},
-		path:  []string{},
+		steps: []lensStep{},
 	}
 }
 
 // L will create a step on a lens to navigate a slice by integer
 func (d *lensBuilder) L(i int) *lensBuilder {
-	d.path = append(d.path, strconv.Itoa(i))
-	d.funcs = append(d.funcs, func(data interface{}) (interface{}, error) {
-		localI := i
-		slice, ok := data.([]interface{})
-
-		if !ok {
-			return nil, NewError(ErrNotFound, "expected a []interface{} type on step %s path %s", strconv.Itoa(localI), strings.Join(d.path, ","))
-		}
+	d.steps = append(d.steps, indexStep{i: i})
+	return d
+}
 
-		if i < 0 || i >= len(slice) {
-			return nil, NewError(ErrNotFound, "not found on step %s path %s", strconv.Itoa(localI), strings.Join(d.path, ","))
-		}
+// M will create a step on a lens to navigate a map by a key
+func (d *lensBuilder) M(key string) *lensBuilder {
+	d.steps = append(d.steps, keyStep{key: key})
+	return d
+}
 
-		return slice[localI], nil
-	})
+// Apply will add a step on a lens to loop over a slice and apply another
+// lens to each element. Elements the lens doesn't find are skipped. If the
+// other lens loops over slices too, its results are flattened.
+func (d *lensBuilder) Apply(l lens) *lensBuilder {
+	d.steps = append(d.steps, applyStep{lens: l})
+	return d
+}
 
+// Where is like Apply but only applies the lens to the maps whose key holds value.
+func (d *lensBuilder) Where(key, value string, l lens) *lensBuilder {
+	d.steps = append(d.steps, applyStep{lens: l, filter: &filter{key: key, value: value}})
 	return d
 }
 
-// M will create a step on a lens to navigate a map by a key
-func (d *lensBuilder) M(key string) *lensBuilder {
-	d.path = append(d.path, key)
-	d.funcs = append(d.funcs, func(data interface{}) (interface{}, error) {
-		localKey := key
+// Build finalizes the lens steps and makes it able to return results.
+func (d *lensBuilder) Build() lens {
+	return lens{
+		steps: append([]lensStep{}, d.steps...),
+	}
+}
+
+// String returns the path of the lens, like spec.containers[*].image.
+func (l lens) String() string {
+	return formatSteps(l.steps)
+}
+
+// formatSteps formats steps as a path.
+func formatSteps(steps []lensStep) string {
+	return strings.TrimPrefix(joinSegments(steps), ".")
+}
+
+// joinSegments joins the path segments of steps.
+func joinSegments(steps []lensStep) string {
+	b := strings.Builder{}
+
+	for _, s := range steps {
+		b.WriteString(s.segment())
+	}
+
+	return b.String()
+}
+
+// collects returns true if the lens loops over a slice.
+func (l lens) collects() bool {
+	for _, s := range l.steps {
+		if _, ok := s.(applyStep); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// step runs the step i of the lens against data.
+func (l lens) step(i int, data interface{}) (interface{}, error) {
+	path := formatSteps(l.steps[:i+1])
+
+	switch s := l.steps[i].(type) {
+	case keyStep:
 		mmap, ok := data.(map[string]interface{})
 
 		if !ok {
-			return nil, NewError(ErrNotFound, "expected a map[string]interface{} type on step %s path %s", localKey, strings.Join(d.path, ","))
+			return nil, NewError(ErrNotFound, "%s: expected a map[string]interface{} type", path)
 		}
 
-		v, ok := mmap[key]
+		v, ok := mmap[s.key]
+
 		if !ok {
-			return nil, NewError(ErrNotFound, "not found on step %s path %s", localKey, strings.Join(d.path, ","))
+			return nil, NewError(ErrNotFound, "%s: not found", path)
 		}
 
 		return v, nil
-	})
-	return d
-}
+	case indexStep:
+		slice, ok := data.([]interface{})
 
-// Apply will add a step on a lens to loop over a slice and apply another
-// lens to each element.
-func (d *lensBuilder) Apply(l lens) *lensBuilder {
-	d.path = append(d.path, "*")
-	d.funcs = append(d.funcs, func(data interface{}) (interface{}, error) {
-		localLens := l
+		if !ok {
+			return nil, NewError(ErrNotFound, "%s: expected a []interface{} type", path)
+		}
+
+		if s.i < 0 || s.i >= len(slice) {
+			return nil, NewError(ErrNotFound, "%s: not found", path)
+		}
+
+		return slice[s.i], nil
+	case applyStep:
 		slice, ok := data.([]interface{})
 
 		if !ok {
-			return nil, NewError(ErrNotFound, "expected a []interface{} type on step * path %s", strings.Join(d.path, ","))
+			return nil, NewError(ErrNotFound, "%s: expected a []interface{} type", formatSteps(l.steps[:i])+"[*]")
 		}
 
 		results := make([]interface{}, 0, len(slice))
 
-		for i := range slice {
-			data := slice[i]
+		for j := range slice {
+			if !s.filter.matches(slice[j]) {
+				continue
+			}
 
-			result, err := localLens.Lookup(data)
+			result, err := s.lens.Lookup(slice[j])
 
 			if err != nil {
 				continue
 			}
 
+			if nested, ok := result.([]interface{}); ok && s.lens.collects() {
+				results = append(results, nested...)
+				continue
+			}
+
 			if result != nil {
 				results = append(results, result)
 			}
 		}
 
 		return results, nil
-	})
-	return d
-}
-
-// Build finalizes the lens steps and makes it able to return results.
-func (d *lensBuilder) Build() lens {
-	funcs := make([]func(interface{}) (interface{}, error), 0, len(d.funcs))
-
-	for i := range d.funcs {
-		localFunc := d.funcs[i]
-		funcs = append(funcs, localFunc)
 	}
 
-	return lens{
-		funcs: funcs,
-	}
+	return nil, NewError(nil, "%s: unknown step", path)
 }
 
 // Lookup will run the lens against data, returning a result or error.
 func (l lens) Lookup(data interface{}) (result interface{}, err error) {
 	result = data
-	for _, fun := range l.funcs {
-		result, err = fun(result)
+	for i := range l.steps {
+		result, err = l.step(i, result)
 
 		if err != nil {
 			return
@@ -127,6 +242,138 @@ func (l lens) Lookup(data interface{}) (result interface{}, err error) {
 	return
 }
 
+// All returns every value the lens finds in data. It's the result of the
+// lookup if the lens loops over slices, or a slice holding the result.
+func (l lens) All(data interface{}) ([]interface{}, error) {
+	result, err := l.Lookup(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if l.collects() {
+		return result.([]interface{}), nil
+	}
+
+	return []interface{}{result}, nil
+}
+
+// Set sets value at the path of the lens in data. Missing maps are created
+// along the way. If the lens loops over a slice, value is set in each element.
+func (l lens) Set(data interface{}, value interface{}) error {
+	return l.update(data, func(parent interface{}, last lensStep, path string) error {
+		switch s := last.(type) {
+		case keyStep:
+			mmap, ok := parent.(map[string]interface{})
+
+			if !ok {
+				return NewError(ErrPathExpectedDifferentType, "%s: expected a map[string]interface{} type", path)
+			}
+
+			mmap[s.key] = value
+		case indexStep:
+			slice, ok := parent.([]interface{})
+
+			if !ok {
+				return NewError(ErrPathExpectedDifferentType, "%s: expected a []interface{} type", path)
+			}
+
+			if s.i < 0 || s.i >= len(slice) {
+				return NewError(ErrNotFound, "%s: not found", path)
+			}
+
+			slice[s.i] = value
+		}
+
+		return nil
+	}, true)
+}
+
+// Delete removes the key at the path of the lens from data. Deleting a
+// missing key is a no-op. Only map keys can be deleted.
+func (l lens) Delete(data interface{}) error {
+	return l.update(data, func(parent interface{}, last lensStep, path string) error {
+		s, ok := last.(keyStep)
+
+		if !ok {
+			return NewError(nil, "%s: only map keys can be deleted", path)
+		}
+
+		if mmap, ok := parent.(map[string]interface{}); ok {
+			delete(mmap, s.key)
+		}
+
+		return nil
+	}, false)
+}
+
+// update navigates data to the parent of the last step of the lens and calls
+// change with it. If create is set, missing maps are created.
+func (l lens) update(data interface{}, change func(parent interface{}, last lensStep, path string) error, create bool) error {
+	if len(l.steps) == 0 {
+		return NewError(nil, "cannot update an empty path")
+	}
+
+	parent := data
+
+	for i, s := range l.steps {
+		if apply, ok := s.(applyStep); ok {
+			if i != len(l.steps)-1 {
+				return NewError(nil, "%s: cannot update values after [*]", formatSteps(l.steps[:i+1]))
+			}
+
+			slice, ok := parent.([]interface{})
+
+			if !ok {
+				if !create {
+					return nil
+				}
+
+				return NewError(ErrPathExpectedDifferentType, "%s: expected a []interface{} type", formatSteps(l.steps[:i])+"[*]")
+			}
+
+			for j := range slice {
+				if !apply.filter.matches(slice[j]) {
+					continue
+				}
+
+				if err := apply.lens.update(slice[j], change, create); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		if i == len(l.steps)-1 {
+			return change(parent, s, formatSteps(l.steps))
+		}
+
+		next, err := l.step(i, parent)
+
+		if err != nil {
+			// there is nothing to delete on a missing path
+			if !create {
+				return nil
+			}
+
+			key, isKey := s.(keyStep)
+			mmap, isMap := parent.(map[string]interface{})
+
+			if !isKey || !isMap {
+				return err
+			}
+
+			next = map[string]interface{}{}
+			mmap[key.key] = next
+		}
+
+		parent = next
+	}
+
+	return nil
+}
+
 // L is an alias for Lookup that will attempt to map the result of the lookup to a slice.
 func (l lens) L(data interface{}) ([]interface{}, error) {
 	answer, err := l.Lookup(data)
@@ -138,7 +385,7 @@ func (l lens) L(data interface{}) ([]interface{}, error) {
 	listAnswer, ok := answer.([]interface{})
 
 	if !ok {
-		return nil, NewError(nil, "expected a []interface{} type")
+		return nil, NewError(nil, "%s: expected a []interface{} type", l)
 	}
 
 	return listAnswer, nil
@@ -163,7 +410,7 @@ func (l lens) M(data interface{}) (map[string]interface{}, error) {
 	mapAnswer, ok := answer.(map[string]interface{})
 
 	if !ok {
-		return nil, NewError(nil, "expected a []interface{} type")
+		return nil, NewError(nil, "%s: expected a map[string]interface{} type", l)
 	}
 
 	return mapAnswer, nil
@@ -176,3 +423,46 @@ func (l lens) MFunc(data interface{}) func() (map[string]interface{}, error) {
 		return l.M(data)
 	}
 }
+
+// MatchesPath returns true if path, the concrete path of a value in data like
+// spec.containers[0].image, is matched by the lens or is under a value matched
+// by the lens.
+func (l lens) MatchesPath(data interface{}, path []interface{}) bool {
+	value := data
+
+	for i, s := range l.steps {
+		if i >= len(path) {
+			return false
+		}
+
+		switch s := s.(type) {
+		case keyStep:
+			if key, ok := path[i].(string); !ok || key != s.key {
+				return false
+			}
+		case indexStep:
+			if index, ok := path[i].(int); !ok || index != s.i {
+				return false
+			}
+		case applyStep:
+			index, ok := path[i].(int)
+			slice, isSlice := value.([]interface{})
+
+			if !ok || !isSlice || index >= len(slice) || !s.filter.matches(slice[index]) {
+				return false
+			}
+
+			return s.lens.MatchesPath(slice[index], path[i+1:])
+		}
+
+		next, err := l.step(i, value)
+
+		if err != nil {
+			return false
+		}
+
+		value = next
+	}
+
+	return true
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParsePath compiles a path like
+// spec.install.spec.deployments[*].spec.template.spec.containers[?name=manager].image
+// to a lens. The path is made of:
+//
+//   - keys, separated by dots, or quoted like metadata.annotations["example.com/image"]
+//   - indexes like [0]
+//   - [*] to loop over every element of a slice
+//   - [?key=value] to loop over the maps of a slice whose key holds value, the
+//     value may be quoted
+//
+// Looping steps apply the rest of the path to each element and flatten the
+// results, see Apply.
+func ParsePath(path string) (lens, error) {
+	steps, err := parseSteps(path)
+
+	if err != nil {
+		return lens{}, err
+	}
+
+	return compileSteps(steps), nil
+}
+
+// ParseParentPath parses a path ending with a key, it returns the lens finding
+// the maps holding the key and the key.
+func ParseParentPath(path string) (lens, string, error) {
+	steps, err := parseSteps(path)
+
+	if err != nil {
+		return lens{}, "", err
+	}
+
+	if len(steps) == 0 {
+		return lens{}, "", NewError(nil, "invalid path %q: empty path", path)
+	}
+
+	last, ok := steps[len(steps)-1].(keyStep)
+
+	if !ok {
+		return lens{}, "", NewError(nil, "invalid path %q: the path must end with a key", path)
+	}
+
+	return compileSteps(steps[:len(steps)-1]), last.key, nil
+}
+
+// compileSteps builds a lens from parsed steps. The steps after a looping step
+// become the lens applied to each element.
+func compileSteps(steps []lensStep) lens {
+	builder := Lens()
+
+	for i, s := range steps {
+		if apply, ok := s.(applyStep); ok {
+			rest := compileSteps(steps[i+1:])
+
+			if apply.filter != nil {
+				return builder.Where(apply.filter.key, apply.filter.value, rest).Build()
+			}
+
+			return builder.Apply(rest).Build()
+		}
+
+		builder.steps = append(builder.steps, s)
+	}
+
+	return builder.Build()
+}
+
+// parseSteps parses a path to a flat list of steps. Looping steps hold an
+// empty lens.
+func parseSteps(path string) ([]lensStep, error) {
+	steps := []lensStep{}
+	rest := path
+
+	invalid := func(format string, args ...interface{}) error {
+		return NewError(nil, "invalid path %q: "+format, append([]interface{}{path}, args...)...)
+	}
+
+	for rest != "" {
+		if rest[0] == '[' {
+			end := closingBracket(rest)
+
+			if end < 0 {
+				return nil, invalid("unterminated [")
+			}
+
+			inner := rest[1:end]
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				steps = append(steps, applyStep{})
+			case strings.HasPrefix(inner, "?"):
+				key, value, ok := strings.Cut(inner[1:], "=")
+
+				if !ok || key == "" {
+					return nil, invalid("invalid filter [%s], expected [?key=value]", inner)
+				}
+
+				if strings.HasPrefix(value, `"`) {
+					unquoted, err := strconv.Unquote(value)
+
+					if err != nil {
+						return nil, invalid("invalid filter value %s", value)
+					}
+
+					value = unquoted
+				}
+
+				steps = append(steps, applyStep{filter: &filter{key: key, value: value}})
+			case strings.HasPrefix(inner, `"`):
+				key, err := strconv.Unquote(inner)
+
+				if err != nil {
+					return nil, invalid("invalid key %s", inner)
+				}
+
+				steps = append(steps, keyStep{key: key})
+			default:
+				i, err := strconv.Atoi(inner)
+
+				if err != nil || i < 0 {
+					return nil, invalid("invalid index [%s]", inner)
+				}
+
+				steps = append(steps, indexStep{i: i})
+			}
+
+			continue
+		}
+
+		if rest[0] == '.' {
+			if len(steps) == 0 {
+				return nil, invalid("unexpected .")
+			}
+
+			rest = rest[1:]
+		} else if len(steps) != 0 {
+			return nil, invalid("expected . or [ before %q", rest)
+		}
+
+		end := strings.IndexAny(rest, ".[")
+
+		if end < 0 {
+			end = len(rest)
+		}
+
+		if end == 0 {
+			return nil, invalid("empty key")
+		}
+
+		steps = append(steps, keyStep{key: rest[:end]})
+		rest = rest[end:]
+	}
+
+	return steps, nil
+}
+
+// closingBracket returns the index of the ] closing the [ at the start of s,
+// skipping quoted strings.
+func closingBracket(s string) int {
+	quoted := false
+
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == ']':
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("path", func() {
+	var data map[string]interface{}
+
+	BeforeEach(func() {
+		data = map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					"example.com/image": "registry.example.com/image:1",
+				},
+			},
+			"deployments": []interface{}{
+				map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "manager", "image": "manager:1"},
+						map[string]interface{}{"name": "proxy", "image": "proxy:1"},
+					},
+				},
+				map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "manager", "image": "manager:2"},
+					},
+				},
+			},
+		}
+	})
+
+	DescribeTable("lookups",
+		func(path string, expected interface{}) {
+			l, err := ParsePath(path)
+			Expect(err).To(Succeed())
+			Expect(l.Lookup(data)).To(Equal(expected))
+		},
+		Entry("keys", "deployments[1].containers[0].name", "manager"),
+		Entry("quoted keys", `metadata.annotations["example.com/image"]`, "registry.example.com/image:1"),
+		Entry("wildcards", "deployments[*].containers[*].image", []interface{}{"manager:1", "proxy:1", "manager:2"}),
+		Entry("filters", "deployments[*].containers[?name=manager].image", []interface{}{"manager:1", "manager:2"}),
+		Entry("quoted filters", `deployments[0].containers[?name="proxy"].image`, []interface{}{"proxy:1"}),
+	)
+
+	DescribeTable("invalid paths",
+		func(path string) {
+			_, err := ParsePath(path)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("leading dot", ".spec"),
+		Entry("empty key", "spec..image"),
+		Entry("unterminated index", "spec[0"),
+		Entry("invalid index", "spec[x]"),
+		Entry("invalid filter", "spec[?name]"),
+		Entry("missing dot", "spec[0]image"),
+	)
+
+	It("should format paths", func() {
+		for _, path := range []string{
+			"deployments[*].containers[?name=manager].image",
+			`metadata.annotations["example.com/image"]`,
+			`deployments[0].containers[?name="a b"]`,
+		} {
+			l, err := ParsePath(path)
+			Expect(err).To(Succeed())
+			Expect(l.String()).To(Equal(path))
+		}
+	})
+
+	It("should return readable errors", func() {
+		l, err := ParsePath("deployments[0].spec.image")
+		Expect(err).To(Succeed())
+
+		_, err = l.Lookup(data)
+		Expect(err).To(MatchError(ErrNotFound))
+		Expect(err.Error()).To(Equal("deployments[0].spec: not found"))
+	})
+
+	It("should set values", func() {
+		l, err := ParsePath("deployments[*].containers[?name=manager].image")
+		Expect(err).To(Succeed())
+		Expect(l.Set(data, "manager:3")).To(Succeed())
+		Expect(l.Lookup(data)).To(Equal([]interface{}{"manager:3", "manager:3"}))
+
+		l, err = ParsePath("spec.relatedImages")
+		Expect(err).To(Succeed())
+		Expect(l.Set(data, []interface{}{})).To(Succeed())
+		Expect(data["spec"]).To(Equal(map[string]interface{}{"relatedImages": []interface{}{}}))
+
+		l, err = ParsePath("metadata.annotations.other.key")
+		Expect(err).To(Succeed())
+		Expect(l.Set(data, "value")).To(Succeed())
+
+		l, err = ParsePath(`metadata.annotations["example.com/image"].key`)
+		Expect(err).To(Succeed())
+		Expect(l.Set(data, "value")).To(MatchError(ErrPathExpectedDifferentType))
+	})
+
+	It("should delete values", func() {
+		l, err := ParsePath("deployments[*].containers[?name=proxy].image")
+		Expect(err).To(Succeed())
+		Expect(l.Delete(data)).To(Succeed())
+		Expect(l.Lookup(data)).To(BeEmpty())
+
+		l, err = ParsePath("spec.missing.key")
+		Expect(err).To(Succeed())
+		Expect(l.Delete(data)).To(Succeed())
+
+		l, err = ParsePath("deployments[0]")
+		Expect(err).To(Succeed())
+		Expect(l.Delete(data)).To(HaveOccurred())
+	})
+
+	It("should split parent paths", func() {
+		l, key, err := ParseParentPath("deployments[*].containers[*].image")
+		Expect(err).To(Succeed())
+		Expect(key).To(Equal("image"))
+		Expect(l.All(data)).To(HaveLen(3))
+
+		_, _, err = ParseParentPath("deployments[0]")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("MatchesPath", func() {
+	data := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"description": "uses registry.example.com/image:1",
+			"containers": []interface{}{
+				map[string]interface{}{"name": "manager", "image": "manager:1"},
+				map[string]interface{}{"name": "proxy", "image": "proxy:1"},
+			},
+		},
+	}
+
+	DescribeTable("paths",
+		func(pattern string, path []interface{}, expected bool) {
+			l, err := ParsePath(pattern)
+			Expect(err).To(Succeed())
+			Expect(l.MatchesPath(data, path)).To(Equal(expected))
+		},
+		Entry("same path", "spec.description", []interface{}{"spec", "description"}, true),
+		Entry("parent path", "spec", []interface{}{"spec", "description"}, true),
+		Entry("other path", "spec.description", []interface{}{"spec", "containers", 0, "image"}, false),
+		Entry("longer pattern", "spec.description.text", []interface{}{"spec", "description"}, false),
+		Entry("wildcard", "spec.containers[*].image", []interface{}{"spec", "containers", 1, "image"}, true),
+		Entry("index", "spec.containers[0]", []interface{}{"spec", "containers", 1, "image"}, false),
+		Entry("matching filter", "spec.containers[?name=proxy]", []interface{}{"spec", "containers", 1, "image"}, true),
+		Entry("filter", "spec.containers[?name=proxy]", []interface{}{"spec", "containers", 0, "image"}, false),
+	)
+})
@@ -3,22 +3,119 @@ package image
 import (
 	"errors"
 
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
 	"github.com/operator-framework/operator-manifest-tools/pkg/pullspec"
 )
 
-// Extract finds and returns all image names in a manifest
+// pullSpecSource is anything images can be extracted from, like a CSV or a bundle.
+type pullSpecSource interface {
+	GetPullSpecs() ([]*imagename.ImageName, error)
+}
+
+// Extract finds and returns all image names in a manifest. Images shared by
+// several manifests are only returned once.
 func Extract(manifests []*pullspec.OperatorCSV) ([]string, error) {
-	imageNames := make([]string, 0, len(manifests))
+	sources := make([]pullSpecSource, 0, len(manifests))
 	for _, manifest := range manifests {
-		pullSpecs, err := manifest.GetPullSpecs()
+		sources = append(sources, manifest)
+	}
+
+	return extract(sources)
+}
+
+// ExtractBundles finds and returns all image names in the bundles. Images
+// shared by several bundles are only returned once.
+func ExtractBundles(bundles []*pullspec.Bundle) ([]string, error) {
+	sources := make([]pullSpecSource, 0, len(bundles))
+	for _, bundle := range bundles {
+		sources = append(sources, bundle)
+	}
+
+	return extract(sources)
+}
+
+func extract(sources []pullSpecSource) ([]string, error) {
+	imageNames := make([]string, 0, len(sources))
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		pullSpecs, err := source.GetPullSpecs()
 		if err != nil {
 			return nil, errors.New("error getting pullspec: " + err.Error())
 		}
 
 		for _, pullSpec := range pullSpecs {
-			imageNames = append(imageNames, pullSpec.String())
+			imageName := pullSpec.String()
+			if seen[imageName] {
+				continue
+			}
+
+			seen[imageName] = true
+			imageNames = append(imageNames, imageName)
 		}
 	}
 
 	return imageNames, nil
 }
+
+// Reference is an occurrence of an image in the manifests.
+type Reference struct {
+	// Image is the image name.
+	Image string `json:"image"`
+	// Kind is the kind of field holding the image.
+	Kind pullspec.PullSpecKind `json:"kind"`
+	// Name is the name of the pull spec, as used for the CSV relatedImages.
+	Name string `json:"name"`
+	// Owner is the workload holding the image, if any.
+	Owner pullspec.Owner `json:"owner"`
+	// Location is where the image was found.
+	Location pullspec.Location `json:"location"`
+	// Skipped is set if the image is marked as not to be pinned, see
+	// pullspec.SkipImagesAnnotation.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// ExtractDetailed returns every occurrence of an image in the bundles, with the
+// kind of field and the location it was found in. The strings dropped by a
+// suppression follow with the suppressed kind.
+func ExtractDetailed(bundles []*pullspec.Bundle) ([]Reference, error) {
+	references := []Reference{}
+	for _, bundle := range bundles {
+		pullSpecs, err := bundle.NamedPullSpecs()
+		if err != nil {
+			return nil, errors.New("error getting pullspec: " + err.Error())
+		}
+
+		markers, err := bundle.SkipMarkers()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pullSpec := range pullSpecs {
+			references = append(references, Reference{
+				Image:    imagename.Parse(pullSpec.Image()).String(),
+				Kind:     pullSpec.Kind(),
+				Name:     pullSpec.Name(),
+				Owner:    pullSpec.Owner(),
+				Location: pullSpec.Location(),
+				Skipped:  markers.Skips(pullSpec),
+			})
+		}
+
+		suppressed, err := bundle.SuppressedPullSpecs()
+		if err != nil {
+			return nil, errors.New("error getting pullspec: " + err.Error())
+		}
+
+		for _, pullSpec := range suppressed {
+			references = append(references, Reference{
+				Image:    pullSpec.Image(),
+				Kind:     pullSpec.Kind(),
+				Name:     pullSpec.Name(),
+				Owner:    pullSpec.Owner(),
+				Location: pullSpec.Location(),
+			})
+		}
+	}
+
+	return references, nil
+}
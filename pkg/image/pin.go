@@ -6,7 +6,7 @@ import (
 )
 
 // Pin iterates through manifests and replaces all image tags with resolved digests.
-func Pin(resolver imageresolver.ImageResolver, manifests []*pullspec.OperatorCSV) error {
+func Pin(resolver imageresolver.ImageResolver, manifests []*pullspec.OperatorCSV, opts ...pullspec.RelatedImagesOption) error {
 	imageNames, err := Extract(manifests)
 	if err != nil {
 		return err
@@ -16,5 +16,19 @@ func Pin(resolver imageresolver.ImageResolver, manifests []*pullspec.OperatorCSV
 		return err
 	}
 
-	return Replace(manifests, replacements)
+	return Replace(manifests, replacements, opts...)
+}
+
+// PinBundles iterates through the manifests of the bundles and replaces all image tags with resolved digests.
+func PinBundles(resolver imageresolver.ImageResolver, bundles []*pullspec.Bundle, opts ...pullspec.RelatedImagesOption) error {
+	imageNames, err := ExtractBundles(bundles)
+	if err != nil {
+		return err
+	}
+	replacements, err := Resolve(resolver, imageNames)
+	if err != nil {
+		return err
+	}
+
+	return ReplaceBundles(bundles, replacements, opts...)
 }
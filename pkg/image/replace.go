@@ -9,17 +9,23 @@ import (
 )
 
 // Repleamcents contains a mapping of image names to alternative image names that should be used instead.
-// Usually image names with tags to image names with digests.
+// Usually image names with tags to image names with digests. Equivalent image names, like nginx:1 and
+// docker.io/library/nginx:1, match the same replacement.
 type Replacements map[imagename.ImageName]imagename.ImageName
 
-// NewReplacements takes in raw replacements and parses them to image names.
+// NewReplacements takes in raw replacements and parses them to image names, see
+// imagename.ParseStrict. A malformed reference returns an error.
 func NewReplacements(replacements map[string]string) (Replacements, error) {
 	r := make(Replacements, len(replacements))
 	for k, v := range replacements {
-		key := imagename.Parse(k)
-		value := imagename.Parse(v)
-		if key == nil || value == nil {
-			return nil, fmt.Errorf("invalid replacement: (%q => %q)", k, v)
+		key, err := imagename.ParseStrict(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replacement: (%q => %q): %w", k, v, err)
+		}
+
+		value, err := imagename.ParseStrict(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replacement: (%q => %q): %w", k, v, err)
 		}
 
 		r[*key] = *value
@@ -28,15 +34,91 @@ func NewReplacements(replacements map[string]string) (Replacements, error) {
 	return r, nil
 }
 
+// Normalized returns the replacements with the canonical form of the new
+// images, see imagename.ImageName.Canonical.
+func (r Replacements) Normalized() Replacements {
+	normalized := make(Replacements, len(r))
+	for k, v := range r {
+		normalized[k] = v.Canonical()
+	}
+
+	return normalized
+}
+
+// KeepTags returns the replacements with the tag of the original image kept next to
+// the digest of the new one, like quay.io/org/op:v1.4.2@sha256:... for quay.io/org/op:v1.4.2.
+// New images without a digest, or that already have a tag, are left alone.
+func (r Replacements) KeepTags() Replacements {
+	kept := make(Replacements, len(r))
+	for k, v := range r {
+		if k.Tag != "" && !k.HasDigest() && v.HasDigest() {
+			if v.Digest == "" {
+				v.Tag, v.Digest = "", v.Tag
+			}
+
+			if v.Tag == "" {
+				v.Tag = k.Tag
+			}
+		}
+
+		kept[k] = v
+	}
+
+	return kept
+}
+
+// Normalize takes a list of manifests and writes the canonical form of their images,
+// like docker.io/library/nginx:latest for nginx.
+func Normalize(manifests []*pullspec.OperatorCSV) error {
+	for _, manifest := range manifests {
+		if err := manifest.NormalizePullSpecs(); err != nil {
+			return errors.New("failed to normalize: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// NormalizeBundles takes a list of bundles and writes the canonical form of the images
+// of each of their manifests.
+func NormalizeBundles(bundles []*pullspec.Bundle) error {
+	for _, bundle := range bundles {
+		if err := bundle.NormalizePullSpecs(); err != nil {
+			return errors.New("failed to normalize: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
 // Replace takes a list of manifests and replaces the images specified in the replacement mapping.
-func Replace(manifests []*pullspec.OperatorCSV, replacements Replacements) error {
+// The options configure how the relatedImages of the manifests are set. Images are only replaced
+// within the scope and outside the excluded paths of each manifest, see pullspec.ScanConfig.
+func Replace(manifests []*pullspec.OperatorCSV, replacements Replacements, opts ...pullspec.RelatedImagesOption) error {
 	for i := range manifests {
 		manifest := manifests[i]
 		if err := manifest.ReplacePullSpecsEverywhere(replacements); err != nil {
 			return errors.New("failed to replace everywhere: " + err.Error())
 		}
 
-		if err := manifest.SetRelatedImages(); err != nil {
+		if err := manifest.SetRelatedImages(opts...); err != nil {
+			return errors.New("failed to set related images: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ReplaceBundles takes a list of bundles and replaces the images specified in the replacement mapping
+// in each of their manifests. The options configure how the relatedImages of the CSVs are set.
+func ReplaceBundles(bundles []*pullspec.Bundle, replacements Replacements, opts ...pullspec.RelatedImagesOption) error {
+	for i := range bundles {
+		bundle := bundles[i]
+		if err := bundle.ReplacePullSpecs(replacements); err != nil {
+			return errors.New("failed to replace everywhere: " + err.Error())
+		}
+
+		if err := bundle.SetRelatedImages(opts...); err != nil {
 			return errors.New("failed to set related images: " + err.Error())
 		}
 	}
@@ -2,20 +2,31 @@ package image
 
 import (
 	"errors"
-	"strings"
 
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
 	"github.com/operator-framework/operator-manifest-tools/pkg/imageresolver"
 )
 
 // Resolver takes a list of images and returns a mapping of the images to an image name with a digst.
+// A malformed reference returns an error instead of being resolved.
 func Resolve(resolver imageresolver.ImageResolver, references []string) (Replacements, error) {
 	results := make(map[string]string, len(references))
 	for _, ref := range references {
-		if strings.Contains(ref, "@") {
+		name, err := imagename.ParseStrict(ref)
+		if err != nil {
+			return nil, errors.New("error resolving image: " + err.Error())
+		}
+
+		if name.HasDigest() {
 			// Already uses a digest
 			continue
 		}
 
+		if _, ok := results[ref]; ok {
+			// Already resolved
+			continue
+		}
+
 		shaRef, err := resolver.ResolveImageReference(ref)
 		if err != nil {
 			return nil, errors.New("error resolving image: " + err.Error())
@@ -0,0 +1,70 @@
+package image
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+// RewriteRule rewrites the images of a source registry.
+type RewriteRule struct {
+	// Registry is the source registry of the images the rule applies to, like
+	// registry.stage.example.com. docker.io also matches images without a registry.
+	Registry string `json:"registry"`
+	// Target is the registry the images are moved to, the images stay on the
+	// source registry if it's empty.
+	Target string `json:"target,omitempty"`
+	// Enclose is the organization the images are enclosed in, see
+	// imagename.ImageName.Enclose. The images aren't enclosed if it's empty.
+	Enclose string `json:"enclose,omitempty"`
+}
+
+// RewriteRules are the rules applied to images, the first rule of the
+// registry of an image applies.
+type RewriteRules []RewriteRule
+
+// rule returns the rule of the registry of the image.
+func (rules RewriteRules) rule(name *imagename.ImageName) (RewriteRule, bool) {
+	canonical := name.Canonical()
+
+	for _, rule := range rules {
+		if rule.Registry == name.Registry || rule.Registry == canonical.Registry {
+			return rule, true
+		}
+	}
+
+	return RewriteRule{}, false
+}
+
+// Rewrite maps the images to their rewritten form, like registry.stage.example.com/team/op@sha256:...
+// to registry.example.com/partner-org/team-op@sha256:... The tag and the digest of the images are
+// kept. Images no rule applies to are left out. A malformed reference returns an error.
+func Rewrite(references []string, rules RewriteRules) (Replacements, error) {
+	replacements := make(Replacements, len(references))
+	for _, ref := range references {
+		name, err := imagename.ParseStrict(ref)
+		if err != nil {
+			return nil, fmt.Errorf("error rewriting image: %w", err)
+		}
+
+		rule, ok := rules.rule(name)
+		if !ok {
+			continue
+		}
+
+		rewritten := *name
+		if rule.Target != "" {
+			rewritten.Registry = rule.Target
+		}
+
+		if rule.Enclose != "" {
+			rewritten.Enclose(rule.Enclose)
+		}
+
+		if rewritten != *name {
+			replacements[*name] = rewritten
+		}
+	}
+
+	return replacements, nil
+}
@@ -19,8 +19,26 @@ const (
 	ExplicitTag
 	// ExplicitNamespace forces a namespace in the repo, will use "library" if no namespace
 	ExplicitNamespace
+	// TagAndDigest keeps the tag next to the digest in the output string, like repo:tag@sha256:...,
+	// otherwise only the digest is used when the image has both.
+	TagAndDigest
 )
 
+const (
+	// DefaultRegistry is the registry of the references without one.
+	DefaultRegistry = "docker.io"
+	// DefaultNamespace is the namespace of the references to the default registry without one.
+	DefaultNamespace = "library"
+	// DefaultTag is the tag of the references without a tag nor a digest.
+	DefaultTag = "latest"
+)
+
+// legacyRegistries are the other names of the default registry.
+var legacyRegistries = map[string]bool{
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
 const (
 	// invalidImageNameString used in Stringer method to represent invalid ImageName
 	invalidImageNameString = "<invalid>"
@@ -28,7 +46,7 @@ const (
 
 var (
 	// DefaultGetStringOptions is the default set of options
-	DefaultGetStringOptions = Registry | Tag
+	DefaultGetStringOptions = Registry | Tag | TagAndDigest
 
 	// ErrNoImageRepository returns when there is there is no image repository
 	ErrNoImageRepository = errors.New("No image repository specified")
@@ -52,6 +70,9 @@ type ImageName struct {
 	Namespace string
 	Repo      string
 	Tag       string
+	// Digest is the digest of the image when parsed by ParseStrict or when
+	// the image has both a tag and a digest, otherwise Parse keeps it in Tag.
+	Digest string
 }
 
 var _ encoding.TextMarshaler = ImageName{}
@@ -61,9 +82,19 @@ func (name ImageName) MarshalText() ([]byte, error) {
 	return []byte(name.String()), nil
 }
 
-// HasDigest return true if the image uses a digest.
+// HasDigest return true if the image uses a digest, of any supported algorithm, see DigestLength.
 func (imageName *ImageName) HasDigest() bool {
-	return strings.HasPrefix(imageName.Tag, "sha256:")
+	if imageName.Digest != "" {
+		return true
+	}
+
+	i := strings.Index(imageName.Tag, ":")
+	if i < 0 {
+		return false
+	}
+
+	_, ok := DigestLength(imageName.Tag[:i])
+	return ok
 }
 
 // GetRepo returns the repository of the image.
@@ -90,7 +121,13 @@ func (imageName *ImageName) ToString(optionSet FormatOption) (string, error) {
 
 	result := imageName.GetRepo(optionSet)
 
-	if optionSet.Has(Tag) && imageName.Tag != "" {
+	if optionSet.Has(Tag) && imageName.Digest != "" {
+		if imageName.Tag != "" && optionSet.Has(TagAndDigest) {
+			result = fmt.Sprintf("%s:%s", result, imageName.Tag)
+		}
+
+		result = fmt.Sprintf("%s@%s", result, imageName.Digest)
+	} else if optionSet.Has(Tag) && imageName.Tag != "" {
 		if imageName.HasDigest() {
 			result = fmt.Sprintf("%s@%s", result, imageName.Tag)
 		} else {
@@ -107,7 +144,46 @@ func (imageName *ImageName) ToString(optionSet FormatOption) (string, error) {
 	return result, nil
 }
 
-// Enclose will set the organization on the image.
+// Canonical returns the fully-qualified form of the image, so equivalent
+// references like nginx:1.25, docker.io/nginx:1.25 and
+// docker.io/library/nginx:1.25 have the same canonical form. The default
+// registry, the library namespace and the latest tag are filled in. Images
+// from Parse and ParseStrict have the same canonical form.
+func (imageName *ImageName) Canonical() ImageName {
+	result := *imageName
+
+	// Parse keeps the digest in the tag and the nested namespaces in the repo.
+	if result.Digest == "" && strings.Contains(result.Tag, ":") {
+		result.Tag, result.Digest = "", result.Tag
+	}
+
+	if path := result.GetRepo(0); strings.Contains(path, "/") {
+		i := strings.LastIndex(path, "/")
+		result.Namespace, result.Repo = path[:i], path[i+1:]
+	}
+
+	if result.Registry == "" || legacyRegistries[result.Registry] {
+		result.Registry = DefaultRegistry
+	}
+
+	if result.Registry == DefaultRegistry && result.Namespace == "" {
+		result.Namespace = DefaultNamespace
+	}
+
+	if result.Tag == "" && result.Digest == "" {
+		result.Tag = DefaultTag
+	}
+
+	return result
+}
+
+// Equivalent returns true if both images have the same canonical form.
+func (imageName *ImageName) Equivalent(other *ImageName) bool {
+	return imageName.Canonical() == other.Canonical()
+}
+
+// Enclose will set the organization on the image. The namespace is collapsed into
+// the repo, like team/op enclosed in partner-org becomes partner-org/team-op.
 func (imageName *ImageName) Enclose(organization string) {
 	if imageName.Namespace == organization {
 		return
@@ -116,7 +192,8 @@ func (imageName *ImageName) Enclose(organization string) {
 	repoParts := []string{imageName.Repo}
 
 	if imageName.Namespace != "" {
-		repoParts = append([]string{imageName.Namespace}, repoParts...)
+		// nested namespaces are collapsed too
+		repoParts = append([]string{strings.ReplaceAll(imageName.Namespace, "/", "-")}, repoParts...)
 	}
 
 	imageName.Namespace = organization
@@ -139,20 +216,22 @@ func (imageName *ImageName) String() string {
 	return result
 }
 
-// Parse parses the image from a string.
+// Parse parses the image from a string. The first component is the registry
+// if it holds a . or a :, is localhost or has upper case characters, like with
+// ParseStrict.
 func Parse(imageName string) *ImageName {
 	result := &ImageName{}
 
 	s := strings.SplitN(imageName, "/", 3)
-	if len(s) == 2 {
-		if strings.ContainsAny(s[0], ".:") {
-			result.Registry = s[0]
-		} else {
-			result.Namespace = s[0]
-		}
+	if len(s) > 1 && (s[0] == "" || isRegistry(s[0])) {
+		result.Registry, s = s[0], s[1:]
 	} else if len(s) == 3 {
-		result.Registry = s[0]
-		result.Namespace = s[1]
+		// without a registry the last components are kept in the repo
+		s = []string{s[0], s[1] + "/" + s[2]}
+	}
+
+	if len(s) == 2 {
+		result.Namespace = s[0]
 	}
 
 	result.Repo = s[len(s)-1]
@@ -163,6 +242,10 @@ func Parse(imageName string) *ImageName {
 
 		if len(s) != 2 {
 			s = strings.SplitN(result.Repo, ":", 2)
+		} else if i := strings.Index(s[0], ":"); i >= 0 {
+			// repo:tag@digest keeps the tag and the digest apart.
+			result.Repo, result.Tag, result.Digest = s[0][:i], s[0][i+1:], s[1]
+			return result
 		}
 
 		if len(s) == 2 {
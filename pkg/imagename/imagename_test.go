@@ -2,6 +2,7 @@ package imagename
 
 import (
 	"fmt"
+	"strings"
 
 	. "github.com/onsi/ginkgo/extensions/table"
 
@@ -115,6 +116,14 @@ var _ = Describe("ImageNameParse", func() {
 		Entry("3", "spam/fedora", "maps", "maps/spam-fedora", "", ""),
 	)
 
+	It("should collapse nested namespaces when enclosing", func() {
+		imageName, err := ParseStrict("registry.stage.example.com/team/sub/op:v1")
+		Expect(err).To(Succeed())
+
+		imageName.Enclose("partner-org")
+		Expect(imageName.String()).To(Equal("registry.stage.example.com/partner-org/team-sub-op:v1"))
+	})
+
 	It("should compare imagenames", func() {
 		i1 := ImageName{Registry: "foo.com", Namespace: "spam", Repo: "bar", Tag: "1"}
 		i2 := ImageName{Registry: "foo.com", Namespace: "spam", Repo: "bar", Tag: "1"}
@@ -127,3 +136,96 @@ var _ = Describe("ImageNameParse", func() {
 		Expect(i1 != i2).To(BeTrue())
 	})
 })
+
+var _ = Describe("Canonical", func() {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	DescribeTable("fills in the defaults",
+		func(text, expected string) {
+			imageName, err := ParseStrict(text)
+			Expect(err).To(Succeed())
+
+			canonical := imageName.Canonical()
+			Expect(canonical.String()).To(Equal(expected))
+			Expect(Parse(text).Canonical()).To(Equal(canonical))
+		},
+		Entry("repo", "nginx", "docker.io/library/nginx:latest"),
+		Entry("tag", "nginx:1.25", "docker.io/library/nginx:1.25"),
+		Entry("default registry", "docker.io/nginx:1.25", "docker.io/library/nginx:1.25"),
+		Entry("legacy registry", "index.docker.io/library/nginx:1.25", "docker.io/library/nginx:1.25"),
+		Entry("namespace", "team/app", "docker.io/team/app:latest"),
+		Entry("registry", "quay.io/app", "quay.io/app:latest"),
+		Entry("nested namespaces", "quay.io/a/b/c:1", "quay.io/a/b/c:1"),
+		Entry("digest", "nginx@"+digest, "docker.io/library/nginx@"+digest),
+		Entry("canonical", "docker.io/library/nginx:1.25", "docker.io/library/nginx:1.25"),
+	)
+
+	DescribeTable("has the same canonical form with Parse and ParseStrict",
+		func(reference string) {
+			strict, err := ParseStrict(reference)
+			Expect(err).To(Succeed())
+			Expect(Parse(reference).Canonical()).To(Equal(strict.Canonical()))
+		},
+		Entry("repo", "nginx"),
+		Entry("namespace", "team/app:1"),
+		Entry("nested namespaces", "team/sub/app:1"),
+		Entry("registry", "quay.io/team/sub/app:1"),
+		Entry("localhost", "localhost/foo:1"),
+		Entry("localhost with a port", "localhost:5000/foo:1"),
+		Entry("localhost with a namespace", "localhost:5000/team/foo:1"),
+		Entry("upper case registry", "Registry/foo:1"),
+		Entry("digest", "quay.io/team/app@"+digest),
+		Entry("tag and digest", "quay.io/team/app:1@"+digest),
+	)
+
+	It("should tell equivalent references", func() {
+		Expect(Parse("nginx:1.25").Equivalent(Parse("docker.io/library/nginx:1.25"))).To(BeTrue())
+		Expect(Parse("nginx").Equivalent(Parse("docker.io/nginx:latest"))).To(BeTrue())
+		Expect(Parse("nginx:1.25").Equivalent(Parse("quay.io/nginx:1.25"))).To(BeFalse())
+		Expect(Parse("nginx:1.25").Equivalent(Parse("nginx:1.26"))).To(BeFalse())
+	})
+})
+
+var _ = Describe("Tag and digest", func() {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	It("should parse both", func() {
+		imageName := Parse("quay.io/org/op:v1.4.2@" + digest)
+		Expect(*imageName).To(Equal(ImageName{Registry: "quay.io", Namespace: "org", Repo: "op", Tag: "v1.4.2", Digest: digest}))
+		Expect(imageName.HasDigest()).To(BeTrue())
+	})
+
+	It("should format both", func() {
+		imageName := Parse("quay.io/org/op:v1.4.2@" + digest)
+		Expect(imageName.String()).To(Equal("quay.io/org/op:v1.4.2@" + digest))
+
+		result, err := imageName.ToString(Registry | Tag)
+		Expect(err).To(Succeed())
+		Expect(result).To(Equal("quay.io/org/op@" + digest))
+
+		result, err = imageName.ToString(Registry)
+		Expect(err).To(Succeed())
+		Expect(result).To(Equal("quay.io/org/op"))
+	})
+})
+
+var _ = Describe("Digest algorithms", func() {
+	DescribeTable("HasDigest",
+		func(text string, expected bool) {
+			Expect(Parse(text).HasDigest()).To(Equal(expected))
+		},
+		Entry("sha256", "quay.io/team/app@sha256:"+strings.Repeat("a", 64), true),
+		Entry("sha384", "quay.io/team/app@sha384:"+strings.Repeat("a", 96), true),
+		Entry("sha512", "quay.io/team/app@sha512:"+strings.Repeat("a", 128), true),
+		Entry("tag and sha512", "quay.io/team/app:1@sha512:"+strings.Repeat("a", 128), true),
+		Entry("unsupported", "quay.io/team/app@md5:"+strings.Repeat("a", 32), false),
+		Entry("tag", "quay.io/team/app:1", false),
+	)
+
+	It("should format sha512 digests", func() {
+		text := "quay.io/team/app@sha512:" + strings.Repeat("a", 128)
+		Expect(Parse(text).String()).To(Equal(text))
+		Expect(IsDigest("sha512:" + strings.Repeat("a", 128))).To(BeTrue())
+		Expect(IsDigest("sha512:" + strings.Repeat("a", 64))).To(BeFalse())
+	})
+})
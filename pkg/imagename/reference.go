@@ -0,0 +1,184 @@
+package imagename
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxNameLength is the maximum length of the name of a reference, its
+// registry and repository.
+const maxNameLength = 255
+
+var (
+	// ErrNameEmpty returns when the reference is empty.
+	ErrNameEmpty = errors.New("repository name must have at least one component")
+	// ErrNameTooLong returns when the name of the reference is longer than 255 characters.
+	ErrNameTooLong = fmt.Errorf("repository name must not be more than %d characters", maxNameLength)
+	// ErrNameContainsUppercase returns when the repository of the reference holds upper case characters.
+	ErrNameContainsUppercase = errors.New("repository name must be lowercase")
+	// ErrReferenceInvalidFormat returns when the registry or the repository of the reference is malformed.
+	ErrReferenceInvalidFormat = errors.New("invalid reference format")
+	// ErrTagInvalidFormat returns when the tag of the reference is malformed.
+	ErrTagInvalidFormat = errors.New("invalid tag format")
+	// ErrDigestInvalidFormat returns when the digest of the reference is malformed.
+	ErrDigestInvalidFormat = errors.New("invalid digest format")
+	// ErrDigestUnsupported returns when the digest algorithm of the reference is unknown.
+	ErrDigestUnsupported = errors.New("unsupported digest algorithm")
+	// ErrDigestInvalidLength returns when the digest doesn't have the length of its algorithm.
+	ErrDigestInvalidLength = errors.New("invalid digest length")
+)
+
+// ParseError is returned by ParseStrict for a malformed reference, Err is one
+// of the Err* errors of the package.
+type ParseError struct {
+	Reference string
+	Err       error
+}
+
+// Error returns the error message.
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("invalid image reference %q: %v", err.Reference, err.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+var (
+	domainRegexp        = regexp.MustCompile(`^(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*|\[[a-fA-F0-9:]+\])(?::[0-9]+)?$`)
+	pathComponentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*$`)
+	tagRegexp           = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp        = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+	hexRegexp           = regexp.MustCompile(`^[a-f0-9]+$`)
+)
+
+// digestLengths are the number of hex characters of the digest algorithms.
+var digestLengths = map[string]int{
+	"sha256": 64,
+	"sha384": 96,
+	"sha512": 128,
+}
+
+// DigestLength returns the number of hex characters of the digests of the
+// algorithm, like 64 for sha256, and false if the algorithm isn't supported.
+func DigestLength(algorithm string) (int, bool) {
+	length, ok := digestLengths[algorithm]
+	return length, ok
+}
+
+// IsDigest returns true if digest is a valid digest of a supported algorithm,
+// like sha256:<64 hex> or sha512:<128 hex>.
+func IsDigest(digest string) bool {
+	return validateDigest(digest) == nil
+}
+
+// ParseStrict parses the image from a string following the distribution
+// reference grammar, [registry/][namespace/...]repo[:tag][@digest]. Unlike
+// Parse, the namespace holds every path component but the last, the tag is
+// left empty when there's none and the digest is kept apart from the tag. The
+// first component is the registry if it holds a . or a :, or is localhost.
+// A malformed reference returns a *ParseError.
+func ParseStrict(reference string) (*ImageName, error) {
+	result, err := parseStrict(reference)
+
+	if err != nil {
+		return nil, &ParseError{Reference: reference, Err: err}
+	}
+
+	return result, nil
+}
+
+func parseStrict(reference string) (*ImageName, error) {
+	if reference == "" {
+		return nil, ErrNameEmpty
+	}
+
+	result := &ImageName{}
+	name := reference
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		if err := validateDigest(name[i+1:]); err != nil {
+			return nil, err
+		}
+
+		name, result.Digest = name[:i], name[i+1:]
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		if !tagRegexp.MatchString(name[i+1:]) {
+			return nil, ErrTagInvalidFormat
+		}
+
+		name, result.Tag = name[:i], name[i+1:]
+	}
+
+	if name == "" {
+		return nil, ErrNameEmpty
+	}
+
+	if len(name) > maxNameLength {
+		return nil, ErrNameTooLong
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 && isRegistry(name[:i]) {
+		if !domainRegexp.MatchString(name[:i]) {
+			return nil, ErrReferenceInvalidFormat
+		}
+
+		name, result.Registry = name[i+1:], name[:i]
+	}
+
+	components := strings.Split(name, "/")
+
+	for _, component := range components {
+		if pathComponentRegexp.MatchString(component) {
+			continue
+		}
+
+		if pathComponentRegexp.MatchString(strings.ToLower(component)) {
+			return nil, ErrNameContainsUppercase
+		}
+
+		return nil, ErrReferenceInvalidFormat
+	}
+
+	result.Namespace = strings.Join(components[:len(components)-1], "/")
+	result.Repo = components[len(components)-1]
+
+	return result, nil
+}
+
+// isRegistry returns true if the first component of a name is a registry.
+func isRegistry(component string) bool {
+	return strings.ContainsAny(component, ".:") ||
+		component == "localhost" ||
+		strings.ToLower(component) != component
+}
+
+// validateDigest checks the format of the digest and the length of the known
+// algorithms.
+func validateDigest(digest string) error {
+	if !digestRegexp.MatchString(digest) {
+		return ErrDigestInvalidFormat
+	}
+
+	i := strings.Index(digest, ":")
+	length, ok := digestLengths[digest[:i]]
+
+	if !ok {
+		return ErrDigestUnsupported
+	}
+
+	if !hexRegexp.MatchString(digest[i+1:]) {
+		return ErrDigestInvalidFormat
+	}
+
+	if len(digest[i+1:]) != length {
+		return ErrDigestInvalidLength
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package imagename
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseStrict", func() {
+	sha256 := "sha256:" + strings.Repeat("a", 64)
+	sha512 := "sha512:" + strings.Repeat("b", 128)
+
+	DescribeTable("parses",
+		func(text string, expected ImageName) {
+			imageName, err := ParseStrict(text)
+			Expect(err).To(Succeed())
+			Expect(*imageName).To(Equal(expected))
+			Expect(imageName.String()).To(Equal(text))
+		},
+		Entry("repo", "fedora",
+			ImageName{Repo: "fedora"}),
+		Entry("tag", "fedora:20",
+			ImageName{Repo: "fedora", Tag: "20"}),
+		Entry("namespace", "library/fedora:20",
+			ImageName{Namespace: "library", Repo: "fedora", Tag: "20"}),
+		Entry("registry", "quay.io/fedora",
+			ImageName{Registry: "quay.io", Repo: "fedora"}),
+		Entry("nested namespaces", "quay.io/a/b/c:tag",
+			ImageName{Registry: "quay.io", Namespace: "a/b", Repo: "c", Tag: "tag"}),
+		Entry("port", "registry:5000/team/app:1.0",
+			ImageName{Registry: "registry:5000", Namespace: "team", Repo: "app", Tag: "1.0"}),
+		Entry("localhost", "localhost/app",
+			ImageName{Registry: "localhost", Repo: "app"}),
+		Entry("IPv6", "[::1]:5000/app:1",
+			ImageName{Registry: "[::1]:5000", Repo: "app", Tag: "1"}),
+		Entry("digest", "quay.io/team/app@"+sha256,
+			ImageName{Registry: "quay.io", Namespace: "team", Repo: "app", Digest: sha256}),
+		Entry("tag and digest", "quay.io/team/app:v1.4.2@"+sha256,
+			ImageName{Registry: "quay.io", Namespace: "team", Repo: "app", Tag: "v1.4.2", Digest: sha256}),
+		Entry("sha512", "quay.io/team/app@"+sha512,
+			ImageName{Registry: "quay.io", Namespace: "team", Repo: "app", Digest: sha512}),
+		Entry("separators", "quay.io/my_team/my-app__x.y",
+			ImageName{Registry: "quay.io", Namespace: "my_team", Repo: "my-app__x.y"}),
+		Entry("upper case registry", "Quay.io/team/app",
+			ImageName{Registry: "Quay.io", Namespace: "team", Repo: "app"}),
+	)
+
+	DescribeTable("rejects",
+		func(text string, expected error) {
+			_, err := ParseStrict(text)
+			Expect(err).To(MatchError(expected))
+
+			var parseError *ParseError
+			Expect(errors.As(err, &parseError)).To(BeTrue())
+			Expect(parseError.Reference).To(Equal(text))
+		},
+		Entry("empty", "", ErrNameEmpty),
+		Entry("only a tag", ":1", ErrNameEmpty),
+		Entry("upper case", "quay.io/Team/app:1", ErrNameContainsUppercase),
+		Entry("invalid component", "quay.io/team/-app", ErrReferenceInvalidFormat),
+		Entry("empty component", "quay.io/team//app", ErrReferenceInvalidFormat),
+		Entry("invalid registry", "-quay.io/team/app", ErrReferenceInvalidFormat),
+		Entry("invalid tag", "quay.io/team/app:-1", ErrTagInvalidFormat),
+		Entry("empty tag", "quay.io/team/app:", ErrTagInvalidFormat),
+		Entry("too long", "quay.io/"+strings.Repeat("a", 256), ErrNameTooLong),
+		Entry("invalid digest", "quay.io/team/app@sha256", ErrDigestInvalidFormat),
+		Entry("upper case digest", "quay.io/team/app@sha256:"+strings.Repeat("A", 64), ErrDigestInvalidFormat),
+		Entry("unsupported digest", "quay.io/team/app@md5:"+strings.Repeat("a", 32), ErrDigestUnsupported),
+		Entry("short digest", "quay.io/team/app@sha256:123456", ErrDigestInvalidLength),
+		Entry("sha512 length", "quay.io/team/app@sha512:"+strings.Repeat("a", 64), ErrDigestInvalidLength),
+	)
+})
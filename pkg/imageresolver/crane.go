@@ -68,5 +68,5 @@ func (res CraneResolver) ResolveImageReference(imageReference string) (string, e
 		return "", err
 	}
 
-	return name + "@" + digest, nil
+	return withDigest(name, digest)
 }
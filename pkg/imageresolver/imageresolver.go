@@ -10,7 +10,7 @@ import (
 // ImageResolve implements a method of identifying an image reference.
 type ImageResolver interface {
 	// ResolveImageReference will use the image resolver to map an image reference
-	// to the image's SHA256 value from the registry.
+	// to the image's digest from the registry, sha256 or any supported algorithm.
 	ResolveImageReference(imageReference string) (string, error)
 }
 
@@ -97,6 +97,26 @@ func GetResolver(resolver ResolverOption, args map[string]string) (ImageResolver
 	}
 }
 
+// supportedAlgorithm returns true if the digest starts with a supported algorithm, like sha256: or sha512:.
+func supportedAlgorithm(digest string) bool {
+	i := strings.Index(digest, ":")
+	if i < 0 {
+		return false
+	}
+
+	_, ok := imagename.DigestLength(digest[:i])
+	return ok
+}
+
+// withDigest returns the image name pinned to the digest, an error if the digest algorithm isn't supported.
+func withDigest(name, digest string) (string, error) {
+	if !supportedAlgorithm(digest) {
+		return "", fmt.Errorf("%w: %q", imagename.ErrDigestUnsupported, digest)
+	}
+
+	return name + "@" + digest, nil
+}
+
 func getName(imageReference string) (string, error) {
 	name := imagename.Parse(imageReference)
 	return name.ToString(imagename.Registry)
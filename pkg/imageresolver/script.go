@@ -1,14 +1,15 @@
 package imageresolver
 
 import (
-	"fmt"
 	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
 // Script supports using a script/executable as an
-// image resolver. The script only needs to return the digest.
+// image resolver. The script only needs to return the digest, sha256 is
+// assumed when it has no algorithm. Other algorithms than sha256, sha384 and
+// sha512 return an error.
 // Examples of custom resolvers can be found in the hack/resolvers
 // folder on the repo.
 type Script struct {
@@ -34,5 +35,9 @@ func (custom *Script) ResolveImageReference(imageReference string) (string, erro
 	}
 
 	digest := strings.TrimSpace(string(output))
-	return fmt.Sprintf("%s@sha256:%s", imageName, digest), nil
+	if !strings.Contains(digest, ":") {
+		digest = "sha256:" + digest
+	}
+
+	return withDigest(imageName, digest)
 }
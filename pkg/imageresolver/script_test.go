@@ -6,6 +6,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
 )
 
 var _ = Describe("script image resolver", func() {
@@ -36,6 +37,29 @@ exit 1
 		Expect(result).Should(Equal("test@sha256:foo"))
 	})
 
+	It("should keep a supported digest algorithm", func() {
+		sha512Script := filepath.Join(filepath.Dir(goodScript), "sha512.sh")
+		Expect(ioutil.WriteFile(sha512Script, []byte(`#!/bin/bash
+echo "sha512:foo"
+`), 0700)).To(Succeed())
+
+		sut = &Script{path: sha512Script}
+		result, err := sut.ResolveImageReference("test")
+		Expect(err).To(Succeed())
+		Expect(result).Should(Equal("test@sha512:foo"))
+	})
+
+	It("should reject an unsupported digest algorithm", func() {
+		md5Script := filepath.Join(filepath.Dir(goodScript), "md5.sh")
+		Expect(ioutil.WriteFile(md5Script, []byte(`#!/bin/bash
+echo "md5:abc"
+`), 0700)).To(Succeed())
+
+		sut = &Script{path: md5Script}
+		_, err := sut.ResolveImageReference("test")
+		Expect(err).To(MatchError(imagename.ErrDigestUnsupported))
+	})
+
 	It("should fail", func() {
 		sut = &Script{path: badScript}
 		_, err := sut.ResolveImageReference("test")
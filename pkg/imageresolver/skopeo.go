@@ -112,7 +112,7 @@ func (skopeo *Skopeo) ResolveImageReference(imageReference string) (string, erro
 			return "", errors.New("Digest not on response")
 		}
 
-		return fmt.Sprintf("%s@%s", imageName, digest), nil
+		return withDigest(imageName, digest)
 	}
 
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -58,6 +59,23 @@ var _ = Describe("skopeo image resolver", func() {
 		Expect(mockProvider.Calls[1].Arguments.Get(1)).To(Not(ContainElement("--raw")))
 	})
 
+	It("should keep the digest algorithm if version 1", func() {
+		mockRunner.On("CombinedOutput").Return([]byte(`{"schemaVersion": 1}`), nil).Once()
+		mockRunner.On("CombinedOutput").Return([]byte(`{"Digest": "sha512:1"}`), nil).Once()
+
+		resolved, err := sut.ResolveImageReference("example.com/foo/bar:latest")
+		Expect(err).To(Succeed())
+		Expect(resolved).To(Equal("example.com/foo/bar@sha512:1"))
+	})
+
+	It("should fail if the digest algorithm isn't supported", func() {
+		mockRunner.On("CombinedOutput").Return([]byte(`{"schemaVersion": 1}`), nil).Once()
+		mockRunner.On("CombinedOutput").Return([]byte(`{"Digest": "md5:1"}`), nil).Once()
+
+		_, err := sut.ResolveImageReference("example.com/foo/bar:latest")
+		Expect(err).To(MatchError(imagename.ErrDigestUnsupported))
+	})
+
 	It("should not change if digest", func() {
 		mockProvider.On("Command", "skopeo", mock.Anything).Return(mockRunner)
 		mockRunner.On("Run").Return(nil)
@@ -0,0 +1,160 @@
+package pullspec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// almExamplesKey is the CSV annotation holding a JSON array of example
+// custom resources.
+const almExamplesKey = "alm-examples"
+
+// jsonString is a string value of a JSON document.
+type jsonString struct {
+	// path is the path of the value in the document.
+	path []interface{}
+	// value is the decoded string.
+	value string
+	// start and end are the offsets of the raw string, without its quotes.
+	start, end int
+}
+
+// raw returns the string as it is written in the text.
+func (str jsonString) raw(text string) string {
+	return text[str.start:str.end]
+}
+
+// isImageField returns true if the string is the value of a field holding an
+// image, like the image of a container or the spec.*.image of an operand.
+func (str jsonString) isImageField() bool {
+	if len(str.path) == 0 {
+		return false
+	}
+
+	key, _ := str.path[len(str.path)-1].(string)
+
+	return key == "image" && str.value != "" && !strings.ContainsAny(str.value, " \t\n")
+}
+
+// almExamplesPullSpecs returns the offsets of the images found in the
+// alm-examples text. The examples are parsed as JSON: the image fields are
+// images whatever they look like and the heuristic is used on the other
+// strings. The offsets point in the original text so replacing them keeps its
+// formatting. It returns false if the text isn't valid JSON.
+func almExamplesPullSpecs(text string, pullSpecHeuristic Heuristic) ([][]int, bool) {
+	strs, err := jsonStrings(text)
+
+	if err != nil {
+		return nil, false
+	}
+
+	results := [][]int{}
+
+	for _, str := range strs {
+		raw := str.raw(text)
+
+		// escaped strings can't be spliced, they're left to the heuristic
+		if str.isImageField() && raw == str.value {
+			results = append(results, []int{str.start, str.end})
+			continue
+		}
+
+		for _, result := range pullSpecHeuristic(raw) {
+			results = append(results, []int{str.start + result[0], str.start + result[1]})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i][0] < results[j][0]
+	})
+
+	return results, true
+}
+
+// jsonStrings returns every string value of the JSON text, in order.
+func jsonStrings(text string) ([]jsonString, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	strs := []jsonString{}
+
+	var walk func(path []interface{}) error
+
+	walk = func(path []interface{}) error {
+		tok, err := dec.Token()
+
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case json.Delim:
+			for i := 0; dec.More(); i++ {
+				var key interface{} = i
+
+				if tok == '{' {
+					keyTok, err := dec.Token()
+
+					if err != nil {
+						return err
+					}
+
+					key = keyTok
+				}
+
+				if err := walk(append(path[:len(path):len(path)], key)); err != nil {
+					return err
+				}
+			}
+
+			// the closing delimiter
+			_, err := dec.Token()
+			return err
+		case string:
+			end := int(dec.InputOffset()) - 1
+			strs = append(strs, jsonString{
+				path:  path,
+				value: tok,
+				start: openingQuote(text, end) + 1,
+				end:   end,
+			})
+		}
+
+		return nil
+	}
+
+	if err := walk(nil); err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+
+	return strs, nil
+}
+
+// openingQuote returns the offset of the quote opening the JSON string closed
+// by the quote at end.
+func openingQuote(text string, end int) int {
+	for i := end - 1; i >= 0; i-- {
+		if text[i] != '"' {
+			continue
+		}
+
+		backslashes := 0
+
+		for j := i - 1; j >= 0 && text[j] == '\\'; j-- {
+			backslashes++
+		}
+
+		if backslashes%2 == 0 {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,110 @@
+package pullspec
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("alm-examples", func() {
+	const examples = `[
+  {
+    "kind": "Operand",
+    "spec": {
+      "image": "operand",
+      "sidecar": {"image": "quay.io/team/sidecar:1"},
+      "note": "uses registry.example.com/team/other:1 too",
+      "escaped": "\"registry.example.com/team/escaped:1\"",
+      "containers": [{"name": "c", "image": "localhost:5000/app"}]
+    }
+  }
+]`
+
+	newCSV := func(examples string) *OperatorCSV {
+		data := &unstructured.Unstructured{Object: map[string]interface{}{
+			"kind": "ClusterServiceVersion",
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					"alm-examples": examples,
+				},
+			},
+			"spec": map[string]interface{}{
+				"install": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"deployments": []interface{}{},
+					},
+				},
+			},
+		}}
+
+		csv, err := NewOperatorCSV("csv.yaml", data, nil)
+		Expect(err).To(Succeed())
+		return csv
+	}
+
+	images := func(csv *OperatorCSV) []string {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		result := []string{}
+		for _, ps := range pullspecs {
+			result = append(result, ps.Image())
+		}
+		return result
+	}
+
+	It("should find the image fields and guess the other strings", func() {
+		Expect(images(newCSV(examples))).To(ConsistOf(
+			"operand",
+			"quay.io/team/sidecar:1",
+			"registry.example.com/team/other:1",
+			"registry.example.com/team/escaped:1",
+			"localhost:5000/app",
+		))
+	})
+
+	It("should keep the formatting when replacing images", func() {
+		csv := newCSV(examples)
+		Expect(csv.ReplacePullSpecsEverywhere(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("operand"):                             *imagename.Parse("registry.example.com/team/operand@sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+			*imagename.Parse("localhost:5000/app"):                  *imagename.Parse("localhost:5000/app@sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+			*imagename.Parse("registry.example.com/team/escaped:1"): *imagename.Parse("registry.example.com/team/escaped@sha256:3333333333333333333333333333333333333333333333333333333333333333"),
+		})).To(Succeed())
+
+		annotations, err := csvAnnotations.M(csv.data.Object)
+		Expect(err).To(Succeed())
+		Expect(annotations[almExamplesKey]).To(Equal(`[
+  {
+    "kind": "Operand",
+    "spec": {
+      "image": "registry.example.com/team/operand@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+      "sidecar": {"image": "quay.io/team/sidecar:1"},
+      "note": "uses registry.example.com/team/other:1 too",
+      "escaped": "\"registry.example.com/team/escaped@sha256:3333333333333333333333333333333333333333333333333333333333333333\"",
+      "containers": [{"name": "c", "image": "localhost:5000/app@sha256:2222222222222222222222222222222222222222222222222222222222222222"}]
+    }
+  }
+]`))
+	})
+
+	It("should fall back to the heuristic on invalid JSON", func() {
+		Expect(images(newCSV(`[{"image": "operand"}, registry.example.com/team/other:1`))).To(
+			ConsistOf("registry.example.com/team/other:1"))
+	})
+
+	It("should return the strings of a JSON document", func() {
+		const text = `{"a": ["x", {"b": "y\"z"}], "c": 1}`
+		strs, err := jsonStrings(text)
+		Expect(err).To(Succeed())
+		Expect(strs).To(HaveLen(2))
+		Expect(strs[0].path).To(Equal([]interface{}{"a", 0}))
+		Expect(strs[0].raw(text)).To(Equal("x"))
+		Expect(strs[1].path).To(Equal([]interface{}{"a", 1, "b"}))
+		Expect(strs[1].value).To(Equal(`y"z`))
+		Expect(strs[1].raw(text)).To(Equal(`y\"z`))
+
+		_, err = jsonStrings(`{"a": 1} {}`)
+		Expect(err).To(HaveOccurred())
+	})
+})
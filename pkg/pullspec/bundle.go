@@ -0,0 +1,346 @@
+package pullspec
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+// Bundle represents a directory holding the manifests of a single operator
+// bundle, like the manifests directory of a bundle image or a version
+// directory of a package-manifests tree.
+type Bundle struct {
+	// Path is the directory the bundle manifests were read from.
+	Path string
+	// CSVs holds the ClusterServiceVersion of the bundle.
+	CSVs []*OperatorCSV
+	// Manifests holds the other kubernetes objects of the bundle. It is only
+	// filled when the bundle is loaded WithAllManifests.
+	Manifests []*Manifest
+}
+
+// BundleOption configures how bundles are loaded.
+type BundleOption func(*bundleOptions)
+
+type bundleOptions struct {
+	allManifests  bool
+	locatorConfig *LocatorConfig
+	scanConfig    *ScanConfig
+}
+
+// WithAllManifests loads every kubernetes object of the bundles, not only the
+// ClusterServiceVersion, so the images they reference are pinned too.
+func WithAllManifests() BundleOption {
+	return func(opts *bundleOptions) {
+		opts.allManifests = true
+	}
+}
+
+// WithLocatorConfig applies the locator config to the CSV of each bundle.
+func WithLocatorConfig(config *LocatorConfig) BundleOption {
+	return func(opts *bundleOptions) {
+		opts.locatorConfig = config
+	}
+}
+
+// WithScanConfig sets where images are looked for in the manifests of each
+// bundle.
+func WithScanConfig(config *ScanConfig) BundleOption {
+	return func(opts *bundleOptions) {
+		opts.scanConfig = config
+	}
+}
+
+// BundlesFromDirectory finds every ClusterServiceVersion under the directory path
+// and groups them by the directory they're in. Each of those directories is
+// treated as a bundle and may only hold a single CSV. The bundles are sorted by
+// their path.
+func BundlesFromDirectory(path string, pullSpecHeuristic Heuristic, opts ...BundleOption) ([]*Bundle, error) {
+	options := bundleOptions{}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	operatorCSVs, manifests, err := findDocuments(path, pullSpecHeuristic)
+
+	if err != nil {
+		return nil, err
+	}
+
+	linkConfigMaps(operatorCSVs, manifests)
+
+	if options.locatorConfig != nil {
+		for _, csv := range operatorCSVs {
+			if err := options.locatorConfig.Apply(csv); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if options.scanConfig != nil {
+		for _, csv := range operatorCSVs {
+			if err := options.scanConfig.apply(csv.document); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, manifest := range manifests {
+			if err := options.scanConfig.apply(manifest.document); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(operatorCSVs) == 0 {
+		log.Printf("failure to find operator manifests in the directory")
+		return nil, utils.ErrNoOperatorManifests
+	}
+
+	byDir := map[string]*Bundle{}
+	bundles := []*Bundle{}
+
+	for _, csv := range operatorCSVs {
+		dir := filepath.Dir(csv.path)
+		bundle, ok := byDir[dir]
+
+		if !ok {
+			bundle = &Bundle{Path: dir}
+			byDir[dir] = bundle
+			bundles = append(bundles, bundle)
+		}
+
+		bundle.CSVs = append(bundle.CSVs, csv)
+	}
+
+	if options.allManifests {
+		for _, manifest := range manifests {
+			bundle, ok := byDir[filepath.Dir(manifest.path)]
+
+			if !ok {
+				log.Printf("skipping manifest outside of a bundle: %s", manifest.path)
+				continue
+			}
+
+			bundle.Manifests = append(bundle.Manifests, manifest)
+		}
+	}
+
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].Path < bundles[j].Path
+	})
+
+	for _, bundle := range bundles {
+		if len(bundle.CSVs) > 1 {
+			log.Printf("found too many csvs in the bundle %s", bundle.Path)
+			return nil, utils.NewError(utils.ErrTooManyCSVs, "%s: %v", bundle.Path, utils.ErrTooManyCSVs)
+		}
+
+		log.Printf("found bundle %s", bundle.Path)
+	}
+
+	return bundles, nil
+}
+
+// OperatorCSVs returns the ClusterServiceVersions of all the bundles.
+func OperatorCSVs(bundles []*Bundle) []*OperatorCSV {
+	operatorCSVs := []*OperatorCSV{}
+
+	for _, bundle := range bundles {
+		operatorCSVs = append(operatorCSVs, bundle.CSVs...)
+	}
+
+	return operatorCSVs
+}
+
+// GetPullSpecs will return a list of all the images found in the bundle.
+func (bundle *Bundle) GetPullSpecs() ([]*imagename.ImageName, error) {
+	seen := make(map[imagename.ImageName]bool)
+	imageList := []*imagename.ImageName{}
+
+	add := func(images []*imagename.ImageName) {
+		for _, image := range images {
+			if seen[*image] {
+				continue
+			}
+
+			seen[*image] = true
+			imageList = append(imageList, image)
+		}
+	}
+
+	for _, csv := range bundle.CSVs {
+		images, err := csv.GetPullSpecs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		add(images)
+	}
+
+	markers, err := bundle.SkipMarkers()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, manifest := range bundle.Manifests {
+		images, err := manifest.GetPullSpecs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		kept := make([]*imagename.ImageName, 0, len(images))
+
+		for _, image := range images {
+			if markers.SkipsImage(image.String()) {
+				log.Printf("%s - Skipping pullspec: %s", manifest.path, image)
+				continue
+			}
+
+			kept = append(kept, image)
+		}
+
+		add(kept)
+	}
+
+	return imageList, nil
+}
+
+// SkipMarkers returns the skip markers of every CSV of the bundle. The skipped
+// images apply to the other manifests too.
+func (bundle *Bundle) SkipMarkers() (*SkipMarkers, error) {
+	markers := newSkipMarkers()
+
+	for _, csv := range bundle.CSVs {
+		csvMarkers, err := csv.SkipMarkers()
+
+		if err != nil {
+			return nil, err
+		}
+
+		markers.merge(csvMarkers)
+	}
+
+	return markers, nil
+}
+
+// NamedPullSpecs returns every pullspec found in the CSV and in the other
+// manifests of the bundle, in the order they were found.
+func (bundle *Bundle) NamedPullSpecs() ([]NamedPullSpec, error) {
+	pullspecs := []NamedPullSpec{}
+
+	for _, csv := range bundle.CSVs {
+		namedList, err := csv.NamedPullSpecs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		pullspecs = append(pullspecs, namedList...)
+	}
+
+	for _, manifest := range bundle.Manifests {
+		namedList, err := manifest.NamedPullSpecs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		pullspecs = append(pullspecs, namedList...)
+	}
+
+	return pullspecs, nil
+}
+
+// ReplacePullSpecs will replace the image values throughout the CSV and in each
+// pullspec of the other manifests.
+func (bundle *Bundle) ReplacePullSpecs(replacement map[imagename.ImageName]imagename.ImageName) error {
+	for _, csv := range bundle.CSVs {
+		if err := csv.ReplacePullSpecsEverywhere(replacement); err != nil {
+			return err
+		}
+	}
+
+	markers, err := bundle.SkipMarkers()
+
+	if err != nil {
+		return err
+	}
+
+	replacement = markers.withoutSkippedImages(replacement)
+
+	for _, manifest := range bundle.Manifests {
+		if err := manifest.ReplacePullSpecs(replacement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetRelatedImages will set the related images fields of the CSV based on the
+// pullspecs discovered in the CSV and in the other manifests.
+func (bundle *Bundle) SetRelatedImages(opts ...RelatedImagesOption) error {
+	extra := []NamedPullSpec{}
+
+	for _, manifest := range bundle.Manifests {
+		pullspecs, err := manifest.NamedPullSpecs()
+
+		if err != nil {
+			return err
+		}
+
+		extra = append(extra, pullspecs...)
+	}
+
+	for _, csv := range bundle.CSVs {
+		if err := csv.setRelatedImages(extra, opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Dump writes the CSV and the other manifests back to the files they were read
+// from. Files holding several documents are only written once.
+func (bundle *Bundle) Dump() error {
+	docs := []*document{}
+
+	for _, csv := range bundle.CSVs {
+		docs = append(docs, csv.document)
+
+		for _, configMap := range csv.configMaps {
+			docs = append(docs, configMap.document)
+		}
+	}
+
+	for _, manifest := range bundle.Manifests {
+		docs = append(docs, manifest.document)
+	}
+
+	dumped := map[*manifestFile]bool{}
+
+	for _, doc := range docs {
+		if doc.file != nil {
+			if dumped[doc.file] {
+				continue
+			}
+
+			dumped[doc.file] = true
+		}
+
+		if err := doc.Dump(nil); err != nil {
+			return errors.New(doc.path + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
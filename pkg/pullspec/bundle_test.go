@@ -0,0 +1,194 @@
+package pullspec
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+var _ = Describe("BundlesFromDirectory", func() {
+	var dir string
+
+	const csv = `apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: foo
+`
+
+	write := func(path, data string) {
+		path = filepath.Join(dir, path)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(data), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "bundles")
+		Expect(err).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	const digest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	It("should group the CSVs by directory", func() {
+		write("1.1.0/csv.yaml", csv)
+		write("1.0.0/csv.yaml", csv)
+		write("1.0.0/crd.yaml", "kind: CustomResourceDefinition\n")
+
+		bundles, err := BundlesFromDirectory(dir, DefaultHeuristic)
+		Expect(err).To(Succeed())
+		Expect(bundles).To(HaveLen(2))
+		Expect(bundles[0].Path).To(Equal(filepath.Join(dir, "1.0.0")))
+		Expect(bundles[0].CSVs).To(HaveLen(1))
+		Expect(bundles[1].Path).To(Equal(filepath.Join(dir, "1.1.0")))
+		Expect(bundles[1].CSVs).To(HaveLen(1))
+		Expect(OperatorCSVs(bundles)).To(Equal([]*OperatorCSV{bundles[0].CSVs[0], bundles[1].CSVs[0]}))
+	})
+
+	It("should fail when a bundle has more than one CSV", func() {
+		write("1.0.0/csv.yaml", csv)
+		write("1.0.0/other.yaml", csv)
+
+		_, err := BundlesFromDirectory(dir, DefaultHeuristic)
+		Expect(err).To(MatchError(utils.ErrTooManyCSVs))
+	})
+
+	Context("with all manifests", func() {
+		const (
+			operatorCSV = csv + `spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: operator
+                image: registry.example.com/operator@sha256:0000000000000000000000000000000000000000000000000000000000000000
+`
+			crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+spec:
+  versions:
+  - name: v1
+    schema:
+      openAPIV3Schema:
+        properties:
+          image:
+            type: string
+            default: registry.example.com/operand:1.0 # keep me
+`
+			configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: images
+data:
+  operand: registry.example.com/operand:1.0
+  other: not an image
+`
+			deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: helper
+spec:
+  template:
+    spec:
+      containers:
+      - name: helper
+        image: registry.example.com/helper:2.0
+`
+		)
+
+		BeforeEach(func() {
+			write("manifests/csv.yaml", operatorCSV)
+			write("manifests/crd.yaml", crd)
+			write("manifests/configmap.yaml", configMap)
+			write("manifests/deployment.yaml", deployment)
+		})
+
+		It("should only load the CSVs by default", func() {
+			bundles, err := BundlesFromDirectory(dir, DefaultHeuristic)
+			Expect(err).To(Succeed())
+			Expect(bundles).To(HaveLen(1))
+			Expect(bundles[0].Manifests).To(BeEmpty())
+		})
+
+		It("should find the images of every manifest", func() {
+			bundles, err := BundlesFromDirectory(dir, DefaultHeuristic, WithAllManifests())
+			Expect(err).To(Succeed())
+			Expect(bundles).To(HaveLen(1))
+			Expect(bundles[0].Manifests).To(HaveLen(3))
+
+			images, err := bundles[0].GetPullSpecs()
+			Expect(err).To(Succeed())
+			Expect(images).To(ConsistOf(
+				imagename.Parse("registry.example.com/operator@sha256:0000000000000000000000000000000000000000000000000000000000000000"),
+				imagename.Parse("registry.example.com/operand:1.0"),
+				imagename.Parse("registry.example.com/helper:2.0"),
+			))
+
+			pullSpecs, err := bundles[0].NamedPullSpecs()
+			Expect(err).To(Succeed())
+
+			owners := map[string]Owner{}
+			for _, ps := range pullSpecs {
+				owners[ps.Image()] = ps.Owner()
+			}
+
+			Expect(owners).To(Equal(map[string]Owner{
+				"registry.example.com/operator@sha256:0000000000000000000000000000000000000000000000000000000000000000": {Deployment: "operator", Container: "operator"},
+				"registry.example.com/operand:1.0": {},
+				"registry.example.com/helper:2.0":  {Deployment: "helper", Container: "helper"},
+			}))
+		})
+
+		It("should replace the images of every manifest", func() {
+			bundles, err := BundlesFromDirectory(dir, DefaultHeuristic, WithAllManifests())
+			Expect(err).To(Succeed())
+
+			bundle := bundles[0]
+			Expect(bundle.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+				*imagename.Parse("registry.example.com/operand:1.0"): *imagename.Parse("registry.example.com/operand@" + digest),
+				*imagename.Parse("registry.example.com/helper:2.0"):  *imagename.Parse("registry.example.com/helper@sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+			})).To(Succeed())
+			Expect(bundle.SetRelatedImages()).To(Succeed())
+			Expect(bundle.Dump()).To(Succeed())
+
+			b, err := os.ReadFile(filepath.Join(dir, "manifests/crd.yaml"))
+			Expect(err).To(Succeed())
+			Expect(string(b)).To(ContainSubstring("default: registry.example.com/operand@" + digest + " # keep me\n"))
+
+			b, err = os.ReadFile(filepath.Join(dir, "manifests/configmap.yaml"))
+			Expect(err).To(Succeed())
+			Expect(string(b)).To(ContainSubstring("operand: registry.example.com/operand@" + digest + "\n"))
+
+			b, err = os.ReadFile(filepath.Join(dir, "manifests/deployment.yaml"))
+			Expect(err).To(Succeed())
+			Expect(string(b)).To(ContainSubstring("image: registry.example.com/helper@sha256:2222222222222222222222222222222222222222222222222222222222222222\n"))
+
+			relatedImages, err := relatedImagesLens.L(bundle.CSVs[0].data.Object)
+			Expect(err).To(Succeed())
+			Expect(relatedImages).To(ConsistOf(
+				map[string]interface{}{"name": "operator", "image": "registry.example.com/operator@sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+				map[string]interface{}{"name": "foos-example-com-operand-11111111111111111111111111111111111111", "image": "registry.example.com/operand@" + digest},
+				map[string]interface{}{"name": "images-operand-111111111111111111111111111111111111111111111111", "image": "registry.example.com/operand@" + digest},
+				map[string]interface{}{"name": "helper-helper", "image": "registry.example.com/helper@sha256:2222222222222222222222222222222222222222222222222222222222222222"},
+			))
+		})
+	})
+
+	It("should fail without CSVs", func() {
+		_, err := BundlesFromDirectory(dir, DefaultHeuristic)
+		Expect(err).To(MatchError(utils.ErrNoOperatorManifests))
+	})
+})
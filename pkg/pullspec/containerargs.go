@@ -0,0 +1,262 @@
+package pullspec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// KindContainerArg is an image passed to a container by a flag of its
+	// command or args.
+	KindContainerArg PullSpecKind = "containerArg"
+	// KindImageEnv is the value of an env var whose name matches the pattern
+	// of the image env locator.
+	KindImageEnv PullSpecKind = "imageEnv"
+)
+
+// The names of the optional locators.
+const (
+	// LocatorContainerArgs finds the images passed as flags in the command and
+	// args of the containers, see NewContainerArgLocator.
+	LocatorContainerArgs = "containerArgs"
+	// LocatorImageEnvs finds the images in env vars not prefixed with
+	// RELATED_IMAGE_, see NewImageEnvLocator.
+	LocatorImageEnvs = "imageEnvs"
+)
+
+var (
+	// DefaultContainerArgPattern matches the flag names ending with image, like
+	// --proxy-image. It's the pattern of the --container-arg-pattern flag
+	// given without a value.
+	DefaultContainerArgPattern = regexp.MustCompile(`(?i)image$`)
+	// DefaultImageEnvPattern matches the env var names ending with _IMAGE, like
+	// OPERAND_IMAGE. It's the pattern of the --image-env-pattern flag given
+	// without a value.
+	DefaultImageEnvPattern = regexp.MustCompile(`_IMAGE$`)
+)
+
+// ContainerArg is a pullspec representing an image passed to a container as a
+// flag, either --flag=image or --flag image.
+type ContainerArg struct {
+	namedPullSpec
+	flag         string
+	index        int
+	startI, endI int
+}
+
+func (arg *ContainerArg) args() []interface{} {
+	args, _ := arg.data[arg.imageKey].([]interface{})
+	return args
+}
+
+// Image returns the image string of the pullspec.
+func (arg *ContainerArg) Image() string {
+	text, _ := arg.args()[arg.index].(string)
+	return text[arg.startI:arg.endI]
+}
+
+// SetImage will replace the image in the arg with the provided image string.
+func (arg *ContainerArg) SetImage(image string) {
+	text, _ := arg.args()[arg.index].(string)
+	arg.args()[arg.index] = text[:arg.startI] + image + text[arg.endI:]
+	arg.imageChanged()
+}
+
+// Kind returns the kind of the pullspec.
+func (arg *ContainerArg) Kind() PullSpecKind {
+	return KindContainerArg
+}
+
+// Name returns the name of the flag, without its dashes.
+func (arg *ContainerArg) Name() string {
+	return strings.ToLower(strings.TrimLeft(arg.flag, "-"))
+}
+
+// String returns a string representation of the pullspec.
+func (arg *ContainerArg) String() string {
+	return fmt.Sprintf("container %s %s %s", arg.namedPullSpec.Name(), arg.imageKey, arg.flag)
+}
+
+// AsYamlObject returns the pullspec as a map[string]interface{}.
+func (arg *ContainerArg) AsYamlObject() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  arg.Name(),
+		"image": arg.Image(),
+	}
+}
+
+func (arg *ContainerArg) imagePath() []interface{} {
+	return []interface{}{arg.imageKey, arg.index}
+}
+
+// NewContainerArgs returns a pullspec for each flag of the container command
+// and args whose name matches flagPattern.
+func NewContainerArgs(container map[string]interface{}, flagPattern *regexp.Regexp) []*ContainerArg {
+	pullspecs := []*ContainerArg{}
+
+	for _, key := range []string{"command", "args"} {
+		args, _ := container[key].([]interface{})
+
+		for i := range args {
+			arg, _ := args[i].(string)
+
+			if !strings.HasPrefix(arg, "-") {
+				continue
+			}
+
+			flag, value, hasValue := strings.Cut(arg, "=")
+
+			if !flagPattern.MatchString(strings.TrimLeft(flag, "-")) {
+				continue
+			}
+
+			ps := &ContainerArg{
+				namedPullSpec: namedPullSpec{imageKey: key, data: container},
+				flag:          flag,
+				index:         i,
+				startI:        len(flag) + 1,
+				endI:          len(arg),
+			}
+
+			// the image is the next arg
+			if !hasValue {
+				if i+1 >= len(args) {
+					continue
+				}
+
+				next, _ := args[i+1].(string)
+
+				if strings.HasPrefix(next, "-") {
+					continue
+				}
+
+				value = next
+				ps.index, ps.startI, ps.endI = i+1, 0, len(next)
+			}
+
+			if value == "" || strings.ContainsAny(value, " \t\n") {
+				continue
+			}
+
+			pullspecs = append(pullspecs, ps)
+		}
+	}
+
+	return pullspecs
+}
+
+// ImageEnv is a pullspec representing an env var holding an image whose name
+// isn't prefixed with RELATED_IMAGE_.
+type ImageEnv struct {
+	RelatedImageEnv
+}
+
+// NewImageEnv returns a new image env pullspec.
+func NewImageEnv(data map[string]interface{}) *ImageEnv {
+	return &ImageEnv{RelatedImageEnv: *NewRelatedImageEnv(data)}
+}
+
+// Kind returns the kind of the pullspec.
+func (imageEnv *ImageEnv) Kind() PullSpecKind {
+	return KindImageEnv
+}
+
+// Name returns the name of the env var, lower cased with dashes.
+func (imageEnv *ImageEnv) Name() string {
+	text := strings.TrimSpace(fmt.Sprintf("%v", imageEnv.env["name"]))
+	return strings.ReplaceAll(strings.ToLower(text), "_", "-")
+}
+
+// String returns a string representation of the pullspec.
+func (imageEnv *ImageEnv) String() string {
+	return fmt.Sprintf("%s var", imageEnv.env["name"])
+}
+
+// AsYamlObject returns the pullspec as a map[string]interface{}.
+func (imageEnv *ImageEnv) AsYamlObject() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  imageEnv.Name(),
+		"image": imageEnv.Image(),
+	}
+}
+
+// allContainers returns the containers and init containers of the CSV
+// deployments.
+func (csv *OperatorCSV) allContainers() ([]map[string]interface{}, error) {
+	containers, err := csv.containerPullSpecs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	initContainers, err := csv.initContainerPullSpecs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+
+	for _, container := range append(containers, initContainers...) {
+		result = append(result, container.Data())
+	}
+
+	return result, nil
+}
+
+// NewContainerArgLocator returns a locator finding the images passed to the
+// containers by the flags of their command or args whose name, without its
+// dashes, matches flagPattern. It isn't registered by default.
+func NewContainerArgLocator(flagPattern *regexp.Regexp) Locator {
+	return &locatorFunc{LocatorContainerArgs, func(csv *OperatorCSV) ([]NamedPullSpec, error) {
+		containers, err := csv.allContainers()
+
+		if err != nil {
+			return nil, err
+		}
+
+		pullspecs := []NamedPullSpec{}
+
+		for _, container := range containers {
+			for _, ps := range NewContainerArgs(container, flagPattern) {
+				pullspecs = append(pullspecs, ps)
+			}
+		}
+
+		return pullspecs, nil
+	}}
+}
+
+// NewImageEnvLocator returns a locator finding the images in the env vars of
+// the containers whose name matches namePattern. RELATED_IMAGE_ env vars and
+// env vars without a value are skipped. It isn't registered by default.
+func NewImageEnvLocator(namePattern *regexp.Regexp) Locator {
+	return &locatorFunc{LocatorImageEnvs, func(csv *OperatorCSV) ([]NamedPullSpec, error) {
+		containers, err := csv.allContainers()
+
+		if err != nil {
+			return nil, err
+		}
+
+		pullspecs := []NamedPullSpec{}
+
+		for _, container := range containers {
+			envs, _ := container["env"].([]interface{})
+
+			for i := range envs {
+				env, _ := envs[i].(map[string]interface{})
+				name, _ := env["name"].(string)
+				value, _ := env["value"].(string)
+
+				if strings.HasPrefix(name, "RELATED_IMAGE_") || value == "" || !namePattern.MatchString(name) {
+					continue
+				}
+
+				pullspecs = append(pullspecs, NewImageEnv(env))
+			}
+		}
+
+		return pullspecs, nil
+	}}
+}
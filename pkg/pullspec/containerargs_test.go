@@ -0,0 +1,121 @@
+package pullspec
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Container args and image envs", func() {
+	const src = `kind: ClusterServiceVersion
+spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+                command:
+                - /manager
+                - --proxy-image=quay.io/team/proxy:v1
+                args:
+                - --leader-elect
+                - --agent-image
+                - agent
+                - --other=quay.io/team/other:1
+                env:
+                - name: OPERAND_IMAGE
+                  value: operand
+                - name: RELATED_IMAGE_DB
+                  value: registry.example.com/team/db:1
+                - name: LOG_LEVEL
+                  value: debug
+`
+
+	var csv *OperatorCSV
+
+	BeforeEach(func() {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err = NewOperatorCSV("csv.yaml", data, nil)
+		Expect(err).To(Succeed())
+	})
+
+	pullspecsOf := func(kind PullSpecKind) []NamedPullSpec {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		result := []NamedPullSpec{}
+		for _, ps := range pullspecs {
+			if ps.Kind() == kind {
+				result = append(result, ps)
+			}
+		}
+		return result
+	}
+
+	It("should be off by default", func() {
+		Expect(pullspecsOf(KindContainerArg)).To(BeEmpty())
+		Expect(pullspecsOf(KindImageEnv)).To(BeEmpty())
+	})
+
+	It("should find images in flags", func() {
+		csv.AddLocator(NewContainerArgLocator(DefaultContainerArgPattern))
+
+		args := pullspecsOf(KindContainerArg)
+		Expect(args).To(HaveLen(2))
+		Expect(args[0].Name()).To(Equal("proxy-image"))
+		Expect(args[0].Image()).To(Equal("quay.io/team/proxy:v1"))
+		Expect(args[0].Owner()).To(Equal(Owner{Deployment: "operator", Container: "manager"}))
+		Expect(args[0].Location().Path).To(Equal("spec.install.spec.deployments[0].spec.template.spec.containers[0].command[1]"))
+		Expect(args[1].Name()).To(Equal("agent-image"))
+		Expect(args[1].Image()).To(Equal("agent"))
+		Expect(args[1].Location().Path).To(Equal("spec.install.spec.deployments[0].spec.template.spec.containers[0].args[2]"))
+
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("quay.io/team/proxy:v1"): *imagename.Parse("quay.io/team/proxy@sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+			*imagename.Parse("agent"):                 *imagename.Parse("registry.example.com/agent@sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+		})).To(Succeed())
+
+		container := args[0].Data()
+		Expect(container["command"]).To(Equal([]interface{}{"/manager", "--proxy-image=quay.io/team/proxy@sha256:1111111111111111111111111111111111111111111111111111111111111111"}))
+		Expect(container["args"]).To(Equal([]interface{}{
+			"--leader-elect", "--agent-image", "registry.example.com/agent@sha256:2222222222222222222222222222222222222222222222222222222222222222", "--other=quay.io/team/other:1",
+		}))
+	})
+
+	It("should find images in env vars", func() {
+		csv.AddLocator(NewImageEnvLocator(DefaultImageEnvPattern))
+
+		envs := pullspecsOf(KindImageEnv)
+		Expect(envs).To(HaveLen(1))
+		Expect(envs[0].Name()).To(Equal("operand-image"))
+		Expect(envs[0].Image()).To(Equal("operand"))
+		Expect(envs[0].Owner()).To(Equal(Owner{Deployment: "operator", Container: "manager"}))
+	})
+
+	It("should name the relatedImages after the flags and env vars", func() {
+		Expect((&LocatorConfig{
+			ContainerArgPattern: "image$",
+			ImageEnvPattern:     "_IMAGE$",
+		}).Apply(csv)).To(Succeed())
+		Expect(csv.SetRelatedImages()).To(Succeed())
+
+		relatedImages, err := relatedImagesLens.L(csv.data.Object)
+		Expect(err).To(Succeed())
+		Expect(relatedImages).To(ContainElements(
+			map[string]interface{}{"name": "proxy-image", "image": "quay.io/team/proxy:v1"},
+			map[string]interface{}{"name": "agent-image", "image": "agent"},
+			map[string]interface{}{"name": "operand-image", "image": "operand"},
+		))
+	})
+})
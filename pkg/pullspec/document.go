@@ -0,0 +1,144 @@
+package pullspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"reflect"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// document holds a kubernetes object of a bundle and the file it was read from.
+type document struct {
+	path              string
+	data              unstructured.Unstructured
+	pullspecHeuristic Heuristic
+
+	// file is the file the document was read from, if any.
+	file *manifestFile
+	// line is the line of the file the document starts on, counting from 0.
+	line int
+
+	// raw and original hold the document text and the data decoded from it,
+	// so changes can be written back without reformatting the whole file.
+	raw      []byte
+	original map[string]interface{}
+
+	// node is the root of the parsed document text, used to locate values.
+	node *yamlv3.Node
+
+	// scope and excludes restrict where pull specs are looked for.
+	scope    Scope
+	excludes []func(data interface{}, path []interface{}) bool
+
+	// suppressions are the strings the heuristic must not report.
+	suppressions *Suppressions
+
+	// index caches the pull specs of a CSV, see OperatorCSV.index.
+	index *pullSpecIndex
+
+	// heuristicResults holds the results of the heuristic by text, the
+	// annotations are scanned by several locators.
+	heuristicResults map[string][][]int
+}
+
+// findPullSpecs returns the bounds of the pullspecs the heuristic finds in
+// text, without the suppressed ones.
+func (doc *document) findPullSpecs(text string) [][]int {
+	results, ok := doc.heuristicResults[text]
+
+	if !ok {
+		if doc.heuristicResults == nil {
+			doc.heuristicResults = map[string][][]int{}
+		}
+
+		results = doc.pullspecHeuristic(text)
+		doc.heuristicResults[text] = results
+	}
+
+	return doc.suppress(text, results)
+}
+
+// changed returns true if the document data differs from what was read.
+func (doc *document) changed() bool {
+	return doc.original == nil || !reflect.DeepEqual(doc.original, doc.data.Object)
+}
+
+// ToYaml will write the document to yaml string and return the bytes.
+// When the document was read from a file only the values that changed are
+// rewritten, everything else is kept byte for byte. Documents read from JSON
+// files are written as JSON, which is valid yaml too.
+func (doc *document) ToYaml() ([]byte, error) {
+	if doc.raw != nil {
+		b, err := patchYaml(doc.raw, doc.original, doc.data.Object)
+		if err == nil {
+			return b, nil
+		}
+
+		log.Printf("%s - unable to keep the original formatting, re-encoding the file: %v", doc.path, err)
+	}
+
+	if doc.file != nil && doc.file.format == jsonFormat {
+		b, err := json.MarshalIndent(&doc.data.Object, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		return append(b, '\n'), nil
+	}
+
+	buff := bytes.Buffer{}
+
+	enc := yamlv3.NewEncoder(&buff)
+	enc.SetIndent(2)
+
+	err := enc.Encode(&doc.data.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// Dump will dump the document yaml to a writer if provided or
+// the file the document started from if the filesystem is writable.
+// When the document was read from a file holding several documents, all of
+// them are written in their original order.
+func (doc *document) Dump(writer io.Writer) error {
+	if doc.file != nil {
+		return doc.file.dump(writer)
+	}
+
+	b, err := doc.ToYaml()
+
+	if err != nil {
+		return err
+	}
+
+	return writeFile(doc.path, writer, b)
+}
+
+// writeFile writes b to the writer if provided or to the file at path.
+func writeFile(path string, writer io.Writer, b []byte) error {
+	if writer == nil {
+		f, err := os.OpenFile(path, os.O_TRUNC|os.O_WRONLY, 0755)
+		if err != nil {
+			return err
+		}
+		defer closeFile(f)
+
+		writer = f
+	}
+
+	_, err := writer.Write(b)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package pullspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+// fileFormat is the format a manifest file is written in.
+type fileFormat int
+
+const (
+	yamlFormat fileFormat = iota
+	jsonFormat
+)
+
+// manifestExtensions are the extensions of the files holding manifests.
+var manifestExtensions = stringSlice{".yaml", ".yml", ".json"}
+
+// isManifestFile returns true if the file name has a manifest extension.
+func isManifestFile(name string) bool {
+	return manifestExtensions.Contains(strings.ToLower(filepath.Ext(name)))
+}
+
+// manifestFile holds the documents of a yaml stream or a json file. The text
+// between documents, like yaml separators, is kept so the file can be written
+// back as it was.
+type manifestFile struct {
+	path   string
+	format fileFormat
+	parts  []filePart
+}
+
+// filePart is either a document or the text around it.
+type filePart struct {
+	text []byte
+	doc  *document
+}
+
+// readFile reads and decodes every document of the file at path.
+func readFile(path string) (*manifestFile, error) {
+	fileData, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file := &manifestFile{path: path}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		file.format = jsonFormat
+		err = file.splitJSON(fileData)
+	} else {
+		err = file.splitYaml(fileData)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// documents returns the documents of the file.
+func (file *manifestFile) documents() []*document {
+	docs := []*document{}
+
+	for _, part := range file.parts {
+		if part.doc != nil {
+			docs = append(docs, part.doc)
+		}
+	}
+
+	return docs
+}
+
+// splitYaml splits a yaml stream on its document separators.
+func (file *manifestFile) splitYaml(fileData []byte) error {
+	start, startLine, offset := 0, 0, 0
+
+	for line, text := range bytes.SplitAfter(fileData, []byte("\n")) {
+		if isYamlSeparator(text) {
+			if err := file.addYamlDocument(fileData[start:offset], startLine); err != nil {
+				return err
+			}
+
+			file.parts = append(file.parts, filePart{text: text})
+			start, startLine = offset+len(text), line+1
+		}
+
+		offset += len(text)
+	}
+
+	return file.addYamlDocument(fileData[start:], startLine)
+}
+
+// isYamlSeparator returns true if the line is a yaml document separator, only
+// followed by blanks or a comment.
+func isYamlSeparator(line []byte) bool {
+	if !bytes.HasPrefix(line, []byte("---")) {
+		return false
+	}
+
+	rest := bytes.TrimSpace(line[3:])
+	return len(rest) == 0 || (rest[0] == '#' && len(line) > 3 && (line[3] == ' ' || line[3] == '\t'))
+}
+
+// addYamlDocument decodes a yaml document starting at line. Documents without
+// content, like comments only, are kept as text.
+func (file *manifestFile) addYamlDocument(body []byte, line int) error {
+	node := yamlv3.Node{}
+	if err := yamlv3.Unmarshal(body, &node); err != nil {
+		return err
+	}
+
+	if node.Kind == 0 || len(node.Content) == 0 {
+		if len(body) != 0 {
+			file.parts = append(file.parts, filePart{text: body})
+		}
+
+		return nil
+	}
+
+	return file.addDocument(body, line)
+}
+
+// splitJSON splits a stream of json objects.
+func (file *manifestFile) splitJSON(fileData []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(fileData))
+	start := 0
+
+	for {
+		value := json.RawMessage{}
+		err := dec.Decode(&value)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		end := int(dec.InputOffset())
+		body := fileData[start:end]
+
+		if err := file.addDocument(body, bytes.Count(fileData[:start], []byte("\n"))); err != nil {
+			return err
+		}
+
+		start = end
+	}
+
+	if start < len(fileData) {
+		file.parts = append(file.parts, filePart{text: fileData[start:]})
+	}
+
+	return nil
+}
+
+// addDocument decodes the kubernetes object held by body. Documents that
+// aren't kubernetes objects, like the references and replacements files
+// written by pin, are kept as text.
+func (file *manifestFile) addDocument(body []byte, line int) error {
+	typeMeta := struct {
+		Kind string `yaml:"kind"`
+	}{}
+
+	if err := yamlv3.Unmarshal(body, &typeMeta); err != nil || typeMeta.Kind == "" {
+		log.Printf("%s - skipping a document without a kind at line %d", file.path, line+1)
+		file.parts = append(file.parts, filePart{text: body})
+		return nil
+	}
+
+	data := &unstructured.Unstructured{}
+
+	// decode YAML into unstructured.Unstructured
+	dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	_, _, err := dec.Decode(body, nil, data)
+
+	if err != nil {
+		return err
+	}
+
+	file.parts = append(file.parts, filePart{doc: &document{
+		path:     file.path,
+		data:     *data,
+		file:     file,
+		line:     line,
+		raw:      body,
+		original: data.DeepCopy().Object,
+	}})
+
+	return nil
+}
+
+// dump writes every document of the file, in their original order, to the
+// writer if provided or to the file.
+func (file *manifestFile) dump(writer io.Writer) error {
+	buff := bytes.Buffer{}
+
+	for _, part := range file.parts {
+		if part.doc == nil {
+			buff.Write(part.text)
+			continue
+		}
+
+		b, err := part.doc.ToYaml()
+
+		if err != nil {
+			return err
+		}
+
+		buff.Write(b)
+	}
+
+	return writeFile(file.path, writer, buff.Bytes())
+}
@@ -0,0 +1,179 @@
+package pullspec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+var _ = Describe("manifest files", func() {
+	var dir string
+
+	const digest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(data), 0600)).To(Succeed())
+		return path
+	}
+
+	read := func(path string) string {
+		b, err := os.ReadFile(path)
+		Expect(err).To(Succeed())
+		return string(b)
+	}
+
+	replacements := map[imagename.ImageName]imagename.ImageName{
+		*imagename.Parse("registry.example.com/foo:1"): *imagename.Parse("registry.example.com/foo@" + digest),
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "files")
+		Expect(err).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should split yaml streams", func() {
+		path := write("stream.yaml", `# leading comment
+---
+kind: ConfigMap
+metadata:
+  name: first
+data:
+  image: registry.example.com/foo:1
+--- # the csv
+kind: ClusterServiceVersion
+metadata:
+  name: csv
+  annotations:
+    containerImage: registry.example.com/foo:1
+---
+`)
+
+		file, err := readFile(path)
+		Expect(err).To(Succeed())
+
+		docs := file.documents()
+		Expect(docs).To(HaveLen(2))
+		Expect(docs[0].data.GetName()).To(Equal("first"))
+		Expect(docs[0].line).To(Equal(2))
+		Expect(docs[1].data.GetName()).To(Equal("csv"))
+		Expect(docs[1].line).To(Equal(8))
+	})
+
+	It("should dump every document of the file in order", func() {
+		path := write("stream.yaml", `kind: ConfigMap
+metadata:
+  name: first
+data:
+  image: registry.example.com/foo:1
+--- # the csv
+kind: ClusterServiceVersion
+metadata:
+  name: csv
+  annotations:
+    containerImage: registry.example.com/foo:1
+spec:
+  install:
+    spec:
+      deployments: []
+`)
+
+		csv, err := NewOperatorCSVFromFile(path, nil)
+		Expect(err).To(Succeed())
+		Expect(csv.ReplacePullSpecsEverywhere(replacements)).To(Succeed())
+		Expect(csv.Dump(nil)).To(Succeed())
+
+		Expect(read(path)).To(Equal(`kind: ConfigMap
+metadata:
+  name: first
+data:
+  image: registry.example.com/foo:1
+--- # the csv
+kind: ClusterServiceVersion
+metadata:
+  name: csv
+  annotations:
+    containerImage: registry.example.com/foo@` + digest + `
+spec:
+  install:
+    spec:
+      deployments: []
+`))
+	})
+
+	It("should write json files as json", func() {
+		const csvJSON = `{
+    "kind": "ClusterServiceVersion",
+    "metadata": {
+        "name": "csv",
+        "annotations": {"containerImage": "registry.example.com/foo:1"}
+    },
+    "spec": {"install": {"spec": {"deployments": []}}}
+}
+`
+		path := write("csv.json", csvJSON)
+
+		csv, err := NewOperatorCSVFromFile(path, nil)
+		Expect(err).To(Succeed())
+		Expect(csv.ReplacePullSpecsEverywhere(replacements)).To(Succeed())
+		Expect(csv.Dump(nil)).To(Succeed())
+
+		Expect(read(path)).To(Equal(`{
+    "kind": "ClusterServiceVersion",
+    "metadata": {
+        "name": "csv",
+        "annotations": {"containerImage": "registry.example.com/foo@` + digest + `"}
+    },
+    "spec": {"install": {"spec": {"deployments": []}}}
+}
+`))
+
+		csv, err = NewOperatorCSVFromFile(path, nil)
+		Expect(err).To(Succeed())
+		csv.data.Object["spec"].(map[string]interface{})["relatedImages"] = []interface{}{}
+		Expect(csv.Dump(nil)).To(Succeed())
+
+		result := map[string]interface{}{}
+		Expect(json.Unmarshal([]byte(read(path)), &result)).To(Succeed())
+		Expect(result).To(Equal(csv.data.Object))
+	})
+
+	It("should find CSVs in json files and yaml streams", func() {
+		write("crd.yaml", `kind: CustomResourceDefinition
+metadata:
+  name: crd
+---
+kind: ClusterServiceVersion
+metadata:
+  name: csv
+`)
+		write("ignored.txt", "kind: ClusterServiceVersion\n")
+
+		csvs, err := FromDirectory(dir, nil)
+		Expect(err).To(Succeed())
+		Expect(csvs).To(HaveLen(1))
+		Expect(csvs[0].data.GetName()).To(Equal("csv"))
+
+		write("references.json", `["registry.example.com/foo:1"]`)
+		write("replacements.json", `{"registry.example.com/foo:1": "registry.example.com/foo@`+digest+`"}`)
+		write("kindless.yaml", "metadata:\n  name: kindless\n")
+
+		csvs, err = FromDirectory(dir, nil)
+		Expect(err).To(Succeed())
+		Expect(csvs).To(HaveLen(1))
+
+		write("other.json", `{"kind": "ClusterServiceVersion", "metadata": {"name": "other"}}`)
+
+		_, err = FromDirectory(dir, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
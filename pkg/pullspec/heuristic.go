@@ -29,16 +29,18 @@ var (
 
 	// A named tag is ':' followed by a basic name
 	namedTag = mustCompileRule(templates, "namedTag", `(?::{{ template "basicName" . }})`)
-	// A digest is "@sha256:" followed by exactly 64 base16 characters
-	digest = mustCompileRule(templates, "digest", `(?:@sha256:{{ .base16 }}{{ print "{64}"}})`)
+	// A digest is "@" followed by a supported algorithm and exactly as many
+	// base16 characters as it needs: 64 for sha256, 96 for sha384 and 128 for
+	// sha512
+	digest = mustCompileRule(templates, "digest", `(?:@(?:sha256:{{ .base16 }}{{ print "{64}"}}|sha384:{{ .base16 }}{{ print "{96}"}}|sha512:{{ .base16 }}{{ print "{128}"}}))`)
 
 	// A tag is either a named tag or a digest
 	tag = mustCompileRule(templates, "tag", `(?:{{ template "namedTag" . }}|{{ template "digest" . }})`)
 
 	// Registry is a basic name that contains at least one dot
-	// followed by an optional port number
+	// followed by an optional port number, or one of the extra registries
 	registry = mustCompileRule(templates, "registry",
-		`(?:{{ .alnum }}{{ .name }}*\.{{ .name }}*{{ .alnum }}(?::\d+)?)`)
+		`(?:{{ .alnum }}{{ .name }}*\.{{ .name }}*{{ .alnum }}(?::\d+)?{{ .registries }})`)
 
 	// Namespace is a basic name
 	namespace = mustCompileRule(templates, "namespace", `{{ template "basicName" . }}`)
@@ -50,16 +52,16 @@ var (
 
 	// Pullspec is registry/namespace*/repo
 	pullspecRule = mustCompileRule(templates, "pullspec", `{{ template "registry" . }}/(?:{{ template "namespace" . }}/)*{{ template "repo" . }}`)
-)
 
-// regexes
-// nolint:unused,deadcode
-var (
-	pullspec  = regexp.MustCompile(mustExecute(templates, "{{template `pullspec` .}}", "alnum", alnum, "name", name, "base16", base16))
-	candidate = regexp.MustCompile(`[a-zA-Z0-9/\-\._@:]+`)
-	full      = regexp.MustCompile(mustExecute(templates, `^{{ template "pullspec" . }}$`, "alnum", alnum, "name", name, "base16", base16))
+	// Untagged pullspec is a pullspec whose tag is optional
+	untaggedPullspecRule = mustCompileRule(templates, "untaggedPullspec",
+		`{{ template "registry" . }}/(?:{{ template "namespace" . }}/)*{{ template "basicName" . }}{{ template "tag" . }}?`)
 )
 
+// candidate matches the sequences of characters that might appear in a
+// pullspec, see NewHeuristic.
+var candidate = regexp.MustCompile(`[a-zA-Z0-9/\-\._@:]+`)
+
 // mustExecute executes a template, panicing on error
 func mustExecute(templates *template.Template, templ string, data ...interface{}) string {
 	if len(data)%2 != 0 {
@@ -105,39 +107,30 @@ type Heuristic func(text string) [][]int
 // - For each such sequence:
 //   - Strip non-alphanumeric characters from both ends
 //   - Match remainder against the pullspec regex
+// The text is scanned in a single pass, without running the regexes, see
+// scanPullSpecs.
 // Put simply, this heuristic should find anything in the form:
 //     registry/namespace*/repo:tag
-//     registry/namespace*/repo@sha256:digest
+//     registry/namespace*/repo@algorithm:digest
 // Where registry must contain at least one '.' and all parts follow various
 // restrictions on the format (most typical pullspecs should be caught). Any
 // number of namespaces, including 0, is valid.
 // NOTE: Pullspecs without a tag (implicitly :latest) will not be caught.
 // This would produce way too many false positives (and 1 false positive
 // is already too many).
+// See NewHeuristic to accept other registries, like localhost, or digests.
 // :param text: Arbitrary blob of text in which to find pullspecs
 // :return: Slice of []int{start, end} tuples of substring indices
 func DefaultHeuristic(text string) [][]int {
-	pullspecs := [][]int{}
-
-	candidates := pullspecCandidates(text)
-	for _, bounds := range candidates {
-		i, j := bounds[0], bounds[1]
-		i, j = adjustForArbitraryText(text, i, j)
-
-		candidate := text[i:j]
-		if len(candidate) != 0 && full.Match([]byte(candidate)) {
-			pullspecs = append(pullspecs, []int{i, j})
-			log.Printf("Pull spec heuristic: %s looks like a pullspec\n", candidate)
-		}
+	pullspecs := scanPullSpecs(text)
+
+	for _, bounds := range pullspecs {
+		log.Printf("Pull spec heuristic: %s looks like a pullspec\n", text[bounds[0]:bounds[1]])
 	}
 
 	return pullspecs
 }
 
-func pullspecCandidates(text string) [][]int {
-	return candidate.FindAllIndex([]byte(text), -1)
-}
-
 func adjustForArbitraryText(text string, i, j int) (int, int) {
 	// Strip all non-alphanumeric characters from start and end of pullspec
 	// candidate to account for various structured/unstructured text elements
@@ -2,20 +2,107 @@ package pullspec
 
 import (
 	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
 
 	. "github.com/onsi/ginkgo/extensions/table"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 const sha = "5d141ae1081640587636880dbe8489439353df883379158fa8742d5a3be75475"
 const notB16 = "5d141ae1081640587636880dbe8489439353df883379158fa8742d5a3be7547g"
 
+// full is the pullspec regex of the default heuristic, the test oracle of
+// scanPullSpecs.
+var full = regexp.MustCompile(mustExecute(templates, `^{{ template "pullspec" . }}$`,
+	"alnum", alnum, "name", name, "base16", base16, "registries", ""))
+
+// regexHeuristic is the regex based heuristic the scanner must agree with.
+func regexHeuristic(text string) [][]int {
+	pullspecs := [][]int{}
+
+	for _, bounds := range candidate.FindAllStringIndex(text, -1) {
+		i, j := adjustForArbitraryText(text, bounds[0], bounds[1])
+
+		if i != j && full.MatchString(text[i:j]) {
+			pullspecs = append(pullspecs, []int{i, j})
+		}
+	}
+
+	return pullspecs
+}
+
+// benchmarkText returns a text looking like a large CSV description, with a
+// few images, of about size bytes.
+func benchmarkText(size int) string {
+	paragraph := fmt.Sprintf(`
+The operator manages the lifecycle of the database, see https://docs.example.com/db/v1.2/install.html
+for details. It runs registry.example.com/team/db:1.2.3 and quay.io/team/proxy@sha256:%s
+next to it, about 0.5/2 of the memory is used by the cache: {"image": "registry.example.com/team/agent:v1"}.
+`, sha)
+	b := strings.Builder{}
+
+	for b.Len() < size {
+		b.WriteString(paragraph)
+	}
+
+	return b.String()
+}
+
+func BenchmarkDefaultHeuristic(b *testing.B) {
+	text := benchmarkText(2 << 20)
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		DefaultHeuristic(text)
+	}
+}
+
+func BenchmarkRegexHeuristic(b *testing.B) {
+	text := benchmarkText(2 << 20)
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		regexHeuristic(text)
+	}
+}
+
 var _ = Describe("DefaultPullspecHeuristic", func() {
+	It("should agree with the regexes on random texts", func() {
+		const alphabet = "ab.:/@-_1 f0[\n\"sha256"
+		r := rand.New(rand.NewSource(1))
+
+		for n := 0; n < 20000; n++ {
+			b := make([]byte, r.Intn(40))
+			for i := range b {
+				b[i] = alphabet[r.Intn(len(alphabet))]
+			}
+
+			text := string(b)
+			Expect(scanPullSpecs(text)).To(Equal(regexHeuristic(text)), text)
+		}
+
+		text := benchmarkText(1 << 16)
+		Expect(scanPullSpecs(text)).To(Equal(regexHeuristic(text)))
+	})
+
 	DescribeTable("matches",
 		func(text string, expected []string) {
 			result := DefaultHeuristic(text)
+			Expect(result).To(Equal(regexHeuristic(text)))
 			strs := []string{}
 
 			for _, bounds := range result {
@@ -172,3 +259,74 @@ var _ = Describe("DefaultPullspecHeuristic", func() {
      `, []string{"a.b/c:1", "d.e/f:1", "g.h/i:1"}),
 	)
 })
+
+var _ = Describe("NewHeuristic", func() {
+	sha512 := sha + sha
+
+	matches := func(h Heuristic, text string) []string {
+		strs := []string{}
+
+		for _, bounds := range h(text) {
+			strs = append(strs, text[bounds[0]:bounds[1]])
+		}
+
+		return strs
+	}
+
+	DescribeTable("matches",
+		func(opts []HeuristicOption, text string, expected []string) {
+			Expect(matches(NewHeuristic(opts...), text)).To(ConsistOf(expected))
+		},
+		Entry("default", nil, "a.b/c:1 localhost:5000/c:1", []string{"a.b/c:1"}),
+		Entry("default", nil, "untagged a.b/c", []string{}),
+		Entry("registry hosts", []HeuristicOption{WithRegistryHosts("localhost")},
+			"localhost/c:1 localhost:5000/ns/c:1 registry:5000/c:1", []string{"localhost/c:1", "localhost:5000/ns/c:1"}),
+		Entry("port registries", []HeuristicOption{WithPortRegistries()},
+			"registry:5000/c:1 registry/c:1", []string{"registry:5000/c:1"}),
+		Entry("IPv6 registries", []HeuristicOption{WithIPv6Registries()},
+			"[fd00::1]:5000/ns/c:1 and [::1]/c:1", []string{"[fd00::1]:5000/ns/c:1", "[::1]/c:1"}),
+		Entry("IPv6 registries", []HeuristicOption{WithIPv6Registries()},
+			"[a.b/c:1] x[d.e/f:1] [add]", []string{"a.b/c:1", "d.e/f:1"}),
+		Entry("IPv6 registries off", nil, "[fd00::1]:5000/c:1", []string{}),
+		Entry("sha512", []HeuristicOption{WithSHA512Digests()},
+			fmt.Sprintf("a.b/c@sha512:%s a.b/d@sha256:%s", sha512, sha),
+			[]string{fmt.Sprintf("a.b/c@sha512:%s", sha512), fmt.Sprintf("a.b/d@sha256:%s", sha)}),
+		Entry("sha512 by default", nil, fmt.Sprintf("a.b/c@sha512:%s", sha512), []string{fmt.Sprintf("a.b/c@sha512:%s", sha512)}),
+		Entry("sha384 by default", nil, fmt.Sprintf("a.b/c@sha384:%s", sha[:96-64]+sha), []string{fmt.Sprintf("a.b/c@sha384:%s", sha[:96-64]+sha)}),
+		Entry("sha512 too short", nil, fmt.Sprintf("a.b/c@sha512:%s", sha), []string{}),
+		Entry("unsupported algorithm", nil, fmt.Sprintf("a.b/c@md5:%s", sha[:32]), []string{}),
+		Entry("optional tag", []HeuristicOption{WithRequiredTag(false)}, " a.b/c/d\n", []string{"a.b/c/d"}),
+		Entry("optional tag in a text", []HeuristicOption{WithRequiredTag(false)},
+			"see a.b/c/d and a.b/e:1", []string{"a.b/e:1"}),
+	)
+
+	It("should find the same images as the default heuristic", func() {
+		text := `{"a":"a.b/c:1","b": "d.e/f:1", "c": "g.h/i:1"} 0.5/2:2 https://my-site.com/here.`
+		Expect(matches(NewHeuristic(), text)).To(Equal(matches(DefaultHeuristic, text)))
+	})
+
+	It("should plug into the CSV", func() {
+		data := &unstructured.Unstructured{Object: map[string]interface{}{
+			"kind": "ClusterServiceVersion",
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					"containerImage": "localhost:5000/team/operator",
+				},
+			},
+			"spec": map[string]interface{}{
+				"install": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"deployments": []interface{}{},
+					},
+				},
+			},
+		}}
+
+		csv, err := NewOperatorCSV("csv.yaml", data, NewHeuristic(WithRegistryHosts("localhost"), WithRequiredTag(false)))
+		Expect(err).To(Succeed())
+
+		images, err := csv.GetPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(images).To(ConsistOf(imagename.Parse("localhost:5000/team/operator")))
+	})
+})
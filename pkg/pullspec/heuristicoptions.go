@@ -0,0 +1,170 @@
+package pullspec
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// heuristicOptions configures the heuristic built by NewHeuristic.
+type heuristicOptions struct {
+	registryHosts  []string
+	portRegistries bool
+	ipv6           bool
+	requiredTag    bool
+}
+
+// HeuristicOption configures the heuristic built by NewHeuristic.
+type HeuristicOption func(*heuristicOptions)
+
+// WithRegistryHosts accepts registries named after one of hosts, like
+// localhost or an internal registry without a dot, with an optional port.
+func WithRegistryHosts(hosts ...string) HeuristicOption {
+	return func(opts *heuristicOptions) {
+		opts.registryHosts = append(opts.registryHosts, hosts...)
+	}
+}
+
+// WithPortRegistries accepts registries without a dot as long as they have a
+// port, like registry:5000.
+func WithPortRegistries() HeuristicOption {
+	return func(opts *heuristicOptions) {
+		opts.portRegistries = true
+	}
+}
+
+// WithIPv6Registries accepts IPv6 literal registries, like [fd00::1]:5000.
+func WithIPv6Registries() HeuristicOption {
+	return func(opts *heuristicOptions) {
+		opts.ipv6 = true
+	}
+}
+
+// WithSHA512Digests accepts @sha512: digests, made of 128 base16 characters,
+// on top of the @sha256: ones.
+//
+// Deprecated: sha384 and sha512 digests are accepted by default.
+func WithSHA512Digests() HeuristicOption {
+	return func(opts *heuristicOptions) {}
+}
+
+// WithRequiredTag sets whether images need a tag or a digest, which is the
+// default. When it's off, a text that is a single image, like the value of a
+// known field such as the containerImage annotation, may be untagged. Images
+// in longer texts always need a tag as they would catch too many URLs.
+func WithRequiredTag(required bool) HeuristicOption {
+	return func(opts *heuristicOptions) {
+		opts.requiredTag = required
+	}
+}
+
+// configuredHeuristic is a heuristic built from options.
+type configuredHeuristic struct {
+	candidate, full, untagged *regexp.Regexp
+	ipv6                      bool
+	requiredTag               bool
+}
+
+// NewHeuristic returns a heuristic working like DefaultHeuristic, extended by
+// the options. Without options it returns DefaultHeuristic.
+func NewHeuristic(opts ...HeuristicOption) Heuristic {
+	options := &heuristicOptions{requiredTag: true}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if len(options.registryHosts) == 0 && !options.portRegistries && !options.ipv6 && options.requiredTag {
+		return DefaultHeuristic
+	}
+
+	registries := strings.Builder{}
+
+	for _, host := range options.registryHosts {
+		registries.WriteString(`|(?:` + regexp.QuoteMeta(host) + `(?::\d+)?)`)
+	}
+
+	if options.portRegistries {
+		registries.WriteString(`|(?:(?:` + alnum + name + `*` + alnum + `|` + alnum + `):\d+)`)
+	}
+
+	if options.ipv6 {
+		registries.WriteString(`|(?:\[` + base16 + `*:[a-fA-F0-9:]*\](?::\d+)?)`)
+	}
+
+	data := []interface{}{"alnum", alnum, "name", name, "base16", base16, "registries", registries.String()}
+	rules := template.Must(templates.Clone())
+
+	h := &configuredHeuristic{
+		candidate:   candidate,
+		full:        regexp.MustCompile(mustExecute(rules, `^{{ template "pullspec" . }}$`, data...)),
+		untagged:    regexp.MustCompile(mustExecute(rules, `^{{ template "untaggedPullspec" . }}$`, data...)),
+		ipv6:        options.ipv6,
+		requiredTag: options.requiredTag,
+	}
+
+	if options.ipv6 {
+		// a bracketed IPv6 literal may start the candidate
+		h.candidate = regexp.MustCompile(`(?:\[[a-fA-F0-9:]+\])?[a-zA-Z0-9/\-\._@:]+`)
+	}
+
+	return h.find
+}
+
+// find returns the bounds of the images in text, see DefaultHeuristic.
+func (h *configuredHeuristic) find(text string) [][]int {
+	pullspecs := [][]int{}
+
+	if !h.requiredTag {
+		if i, j := h.single(text); i != j {
+			log.Printf("Pull spec heuristic: %s looks like a pullspec\n", text[i:j])
+			return append(pullspecs, []int{i, j})
+		}
+	}
+
+	for _, bounds := range h.candidate.FindAllStringIndex(text, -1) {
+		i, j := h.adjust(text, bounds[0], bounds[1])
+
+		if i != j {
+			pullspecs = append(pullspecs, []int{i, j})
+			log.Printf("Pull spec heuristic: %s looks like a pullspec\n", text[i:j])
+		}
+	}
+
+	return pullspecs
+}
+
+// single returns the bounds of text, without its surrounding spaces, if it's a
+// single image that may be untagged.
+func (h *configuredHeuristic) single(text string) (int, int) {
+	i := len(text) - len(strings.TrimLeft(text, " \t\r\n"))
+	j := len(strings.TrimRight(text, " \t\r\n"))
+
+	if i < j && h.untagged.MatchString(text[i:j]) {
+		return i, j
+	}
+
+	return 0, 0
+}
+
+// adjust strips the candidate from the characters around it and returns its
+// bounds if it's an image, or equal bounds if it isn't. The opening bracket of
+// an IPv6 registry is kept.
+func (h *configuredHeuristic) adjust(text string, i, j int) (int, int) {
+	if h.ipv6 && text[i] == '[' {
+		_, end := adjustForArbitraryText(text, i, j)
+
+		if h.full.MatchString(text[i:end]) {
+			return i, end
+		}
+	}
+
+	i, j = adjustForArbitraryText(text, i, j)
+
+	if i == j || !h.full.MatchString(text[i:j]) {
+		return i, i
+	}
+
+	return i, j
+}
@@ -0,0 +1,287 @@
+package pullspec
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+)
+
+// pullSpecIndex holds the pull spec occurrences of a CSV. The CSV is walked
+// once when the index is built, the locators and the CSV methods read from the
+// index until the CSV changes, see invalidate.
+type pullSpecIndex struct {
+	relatedImages    []NamedPullSpec
+	relatedImagesErr error
+
+	// containers, initContainers and imageVolumes are found walking the
+	// deployments once.
+	containers     []NamedPullSpec
+	initContainers []NamedPullSpec
+	imageVolumes   []NamedPullSpec
+	deploymentsErr error
+
+	relatedImageEnvs    []NamedPullSpec
+	relatedImageEnvsErr error
+
+	annotations    []map[string]interface{}
+	annotationsErr error
+
+	// named holds the result of NamedPullSpecs once the locators ran.
+	named []NamedPullSpec
+
+	// markers holds the result of SkipMarkers once read.
+	markers    *SkipMarkers
+	markersErr error
+}
+
+// invalidate drops the index of the document, it's rebuilt on next use. It
+// must be called whenever the document or the way it's scanned changes.
+func (doc *document) invalidate() {
+	doc.index = nil
+}
+
+// index returns the index of the CSV, building it if needed.
+func (csv *OperatorCSV) index() *pullSpecIndex {
+	if csv.document.index != nil {
+		return csv.document.index
+	}
+
+	index := &pullSpecIndex{}
+	index.relatedImages, index.relatedImagesErr = csv.findRelatedImages()
+	index.deploymentsErr = csv.findWorkloads(index)
+
+	if index.deploymentsErr == nil {
+		index.relatedImageEnvs, index.relatedImageEnvsErr = csv.findRelatedImageEnvs(
+			append(index.containers[:len(index.containers):len(index.containers)], index.initContainers...))
+	}
+
+	index.annotations, index.annotationsErr = csv.collectAnnotations()
+	csv.document.index = index
+
+	for _, pullspecs := range [][]NamedPullSpec{
+		index.relatedImages, index.containers, index.initContainers, index.imageVolumes, index.relatedImageEnvs,
+	} {
+		csv.attach(pullspecs)
+	}
+
+	return index
+}
+
+// attachable is implemented by the pull specs that drop the index of the CSV
+// when their image is set.
+type attachable interface {
+	attach(*document)
+}
+
+// attach makes the pull specs drop the index of the CSV when their image is
+// set, the cached pull specs and their offsets are stale once it changes.
+func (csv *OperatorCSV) attach(pullspecs []NamedPullSpec) {
+	for _, ps := range pullspecs {
+		if a, ok := ps.(attachable); ok {
+			a.attach(csv.document)
+		}
+	}
+}
+
+// findWorkloads walks the deployments once to find their containers, init
+// containers and image volumes.
+func (csv *OperatorCSV) findWorkloads(index *pullSpecIndex) error {
+	deployments, err := csv.deployments()
+
+	if err != nil {
+		return err
+	}
+
+	for i := range deployments {
+		containers, err := lookupSlice(containerLens.L, deployments[i])
+
+		if err != nil {
+			return err
+		}
+
+		for j := range containers {
+			pullspec, err := NewContainer(containers[j])
+
+			if err != nil {
+				return err
+			}
+
+			index.containers = append(index.containers, pullspec)
+		}
+
+		initContainers, err := lookupSlice(initContainerLens.L, deployments[i])
+
+		if err != nil {
+			return err
+		}
+
+		for j := range initContainers {
+			pullspec, err := NewInitContainer(initContainers[j])
+
+			if err != nil {
+				return err
+			}
+
+			index.initContainers = append(index.initContainers, pullspec)
+		}
+
+		volumes, err := lookupSlice(volumeLens.L, deployments[i])
+
+		if err != nil {
+			return err
+		}
+
+		imageVolumePullSpecs, err := imageVolumes(volumes)
+
+		if err != nil {
+			return err
+		}
+
+		index.imageVolumes = append(index.imageVolumes, imageVolumePullSpecs...)
+	}
+
+	return nil
+}
+
+// lookupSlice returns the slice the lens finds in data, or nil if it's missing.
+func lookupSlice(find func(interface{}) ([]interface{}, error), data interface{}) ([]interface{}, error) {
+	result, err := find(data)
+
+	if errors.Is(err, utils.ErrNotFound) {
+		return nil, nil
+	}
+
+	return result, err
+}
+
+// indexed returns a copy of pullspecs, so callers can't change the index.
+func indexed(pullspecs []NamedPullSpec, err error) ([]NamedPullSpec, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return append(make([]NamedPullSpec, 0, len(pullspecs)), pullspecs...), nil
+}
+
+func (csv *OperatorCSV) relatedImagePullSpecs() ([]NamedPullSpec, error) {
+	index := csv.index()
+	return indexed(index.relatedImages, index.relatedImagesErr)
+}
+
+func (csv *OperatorCSV) containerPullSpecs() ([]NamedPullSpec, error) {
+	index := csv.index()
+	return indexed(index.containers, index.deploymentsErr)
+}
+
+func (csv *OperatorCSV) initContainerPullSpecs() ([]NamedPullSpec, error) {
+	index := csv.index()
+	return indexed(index.initContainers, index.deploymentsErr)
+}
+
+func (csv *OperatorCSV) imageVolumePullSpecs() ([]NamedPullSpec, error) {
+	index := csv.index()
+	return indexed(index.imageVolumes, index.deploymentsErr)
+}
+
+func (csv *OperatorCSV) relatedImageEnvPullSpecs() ([]NamedPullSpec, error) {
+	index := csv.index()
+
+	if index.deploymentsErr != nil {
+		return nil, index.deploymentsErr
+	}
+
+	return indexed(index.relatedImageEnvs, index.relatedImageEnvsErr)
+}
+
+func (csv *OperatorCSV) findAllAnnotations() ([]map[string]interface{}, error) {
+	index := csv.index()
+	return index.annotations, index.annotationsErr
+}
+
+// everywherePullSpecs returns the pull specs ReplacePullSpecsEverywhere
+// replaces: the named pull specs and the images the heuristic finds in the
+// annotations and, in the everywhere scope, in the other strings of the CSV.
+// Strings already holding a named pull spec aren't guessed again. Annotations
+// come last, the latest in their string first, so replacing an image doesn't
+// move the next ones.
+func (csv *OperatorCSV) everywherePullSpecs() ([]NamedPullSpec, error) {
+	named, err := csv.NamedPullSpecs()
+
+	if err != nil || csv.Scope() == ScopeKnown {
+		return named, err
+	}
+
+	guessed, err := csv.annotationPullSpecs(csv.annotationKeys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	guessedAnnotations, err := csv.annotationPullSpecs(nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	guessed = append(guessed, guessedAnnotations...)
+
+	if csv.Scope() == ScopeEverywhere {
+		if err := csv.findPotentialPullSpecsNotInAnnotations(csv.data.Object, &guessed); err != nil {
+			return nil, err
+		}
+	}
+
+	paths := csv.pathIndex()
+	guessed = csv.excluded(guessed, paths)
+	setOwners(guessed, paths, csv.ownerOf)
+
+	type field struct {
+		data uintptr
+		key  string
+	}
+
+	claimed := map[field]bool{}
+	pullspecs := []NamedPullSpec{}
+	annotations := []*Annotation{}
+
+	for _, ps := range named {
+		if annotation, ok := ps.(*Annotation); ok {
+			annotations = append(annotations, annotation)
+			continue
+		}
+
+		if l, ok := ps.(locatable); ok {
+			claimed[field{mapID(ps.Data()), formatPath(l.imagePath())}] = true
+		}
+
+		pullspecs = append(pullspecs, ps)
+	}
+
+	for _, ps := range guessed {
+		annotation, ok := ps.(*Annotation)
+
+		if ok && !claimed[field{mapID(annotation.Data()), formatPath(annotation.imagePath())}] {
+			annotations = append(annotations, annotation)
+		}
+	}
+
+	unique := uniquePullSpecs(annotationSlice(annotations))
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		return unique[i].(*Annotation).startI > unique[j].(*Annotation).startI
+	})
+
+	return append(pullspecs, unique...), nil
+}
+
+// annotationSlice converts annotations to pull specs.
+func annotationSlice(annotations []*Annotation) []NamedPullSpec {
+	pullspecs := make([]NamedPullSpec, 0, len(annotations))
+
+	for _, annotation := range annotations {
+		pullspecs = append(pullspecs, annotation)
+	}
+
+	return pullspecs
+}
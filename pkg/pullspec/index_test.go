@@ -0,0 +1,200 @@
+package pullspec
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+// decodeCSV decodes a CSV from yaml.
+func decodeCSV(src string) (*OperatorCSV, error) {
+	data := &unstructured.Unstructured{}
+	dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+
+	if _, _, err := dec.Decode([]byte(src), nil, data); err != nil {
+		return nil, err
+	}
+
+	return NewOperatorCSV("csv.yaml", data, DefaultHeuristic)
+}
+
+var _ = Describe("Pull spec index", func() {
+	const src = `kind: ClusterServiceVersion
+metadata:
+  annotations:
+    containerImage: registry.example.com/team/operator:1
+    description: Runs registry.example.com/team/operator:1 and registry.example.com/team/agent:1
+spec:
+  description: Uses registry.example.com/team/agent:1, registry.example.com/team/operator:1.
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+                env:
+                - name: RELATED_IMAGE_AGENT
+                  value: registry.example.com/team/agent:1
+`
+
+	var csv *OperatorCSV
+
+	BeforeEach(func() {
+		var err error
+		csv, err = decodeCSV(src)
+		Expect(err).To(Succeed())
+	})
+
+	images := func() []string {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		result := []string{}
+		for _, ps := range pullspecs {
+			result = append(result, ps.Image())
+		}
+		return result
+	}
+
+	It("should be built once", func() {
+		first, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+		index := csv.document.index
+
+		second, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(csv.document.index).To(BeIdenticalTo(index))
+		Expect(second).To(Equal(first))
+
+		first[0] = nil
+		Expect(images()).To(HaveLen(len(second)))
+	})
+
+	It("should be rebuilt when the CSV changes", func() {
+		Expect(csv.HasRelatedImages()).To(BeFalse())
+		Expect(csv.HasRelatedImageEnvs()).To(BeTrue())
+
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("registry.example.com/team/agent:1"): *imagename.Parse("registry.example.com/team/agent:2"),
+		})).To(Succeed())
+		Expect(images()).To(ContainElement("registry.example.com/team/agent:2"))
+		Expect(images()).ToNot(ContainElement("registry.example.com/team/agent:1"))
+
+		Expect(csv.SetRelatedImages()).To(Succeed())
+		Expect(csv.HasRelatedImages()).To(BeTrue())
+	})
+
+	It("should be rebuilt when the locators change", func() {
+		Expect(images()).To(ContainElement("registry.example.com/team/agent:1"))
+		Expect(csv.DisableLocator(LocatorRelatedImageEnvs)).To(Succeed())
+		Expect(csv.DisableLocator(LocatorGuessedAnnotations)).To(Succeed())
+		Expect(images()).ToNot(ContainElement("registry.example.com/team/agent:1"))
+	})
+
+	It("should be rebuilt when the image of a pull spec is set", func() {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		for _, ps := range pullspecs {
+			if annotation, ok := ps.(*Annotation); ok && annotation.imageKey == "description" && ps.Image() == "registry.example.com/team/operator:1" {
+				ps.SetImage("registry.example.com/team/operator-with-a-longer-name:1")
+				break
+			}
+		}
+
+		Expect(csv.data.GetAnnotations()["description"]).To(Equal(
+			"Runs registry.example.com/team/operator-with-a-longer-name:1 and registry.example.com/team/agent:1"))
+		Expect(images()).To(ContainElements(
+			"registry.example.com/team/operator-with-a-longer-name:1",
+			"registry.example.com/team/agent:1",
+		))
+
+		extracted, err := csv.GetPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(extracted).To(ContainElements(
+			imagename.Parse("registry.example.com/team/operator-with-a-longer-name:1"),
+			imagename.Parse("registry.example.com/team/agent:1"),
+		))
+
+		envs, err := csv.relatedImageEnvPullSpecs()
+		Expect(err).To(Succeed())
+		envs[0].SetImage("registry.example.com/team/agent:2")
+
+		Expect(images()).To(ContainElement("registry.example.com/team/agent:2"))
+	})
+
+	It("should replace every occurrence in a single pass", func() {
+		Expect(csv.ReplacePullSpecsEverywhere(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("registry.example.com/team/operator:1"): *imagename.Parse("registry.example.com/team/operator:1.0.1"),
+			*imagename.Parse("registry.example.com/team/agent:1"):    *imagename.Parse("registry.example.com/team/agent:1.0.1"),
+		})).To(Succeed())
+
+		annotations := csv.data.GetAnnotations()
+		Expect(annotations["containerImage"]).To(Equal("registry.example.com/team/operator:1.0.1"))
+		Expect(annotations["description"]).To(Equal("Runs registry.example.com/team/operator:1.0.1 and registry.example.com/team/agent:1.0.1"))
+		Expect(csv.data.Object["spec"].(map[string]interface{})["description"]).
+			To(Equal("Uses registry.example.com/team/agent:1.0.1, registry.example.com/team/operator:1.0.1."))
+		Expect(images()).To(ContainElements(
+			"registry.example.com/team/operator:1.0.1",
+			"registry.example.com/team/agent:1.0.1",
+		))
+		Expect(images()).ToNot(ContainElement("registry.example.com/team/operator:1"))
+	})
+})
+
+func BenchmarkNamedPullSpecs(b *testing.B) {
+	src := strings.Builder{}
+	src.WriteString("kind: ClusterServiceVersion\nspec:\n  install:\n    spec:\n      deployments:\n")
+
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&src, `      - name: operator-%[1]d
+        spec:
+          template:
+            metadata:
+              annotations:
+                description: Runs registry.example.com/team/operand-%[1]d:1
+            spec:
+              containers:
+              - name: manager-%[1]d
+                image: registry.example.com/team/operator-%[1]d:1
+                env:
+                - name: RELATED_IMAGE_OPERAND_%[1]d
+                  value: registry.example.com/team/operand-%[1]d:1
+`, i)
+	}
+
+	csv, err := decodeCSV(src.String())
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		csv.invalidate()
+
+		if _, err := csv.GetPullSpecs(); err != nil {
+			b.Fatal(err)
+		}
+
+		if err := csv.SetRelatedImages(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
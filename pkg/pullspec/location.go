@@ -0,0 +1,230 @@
+package pullspec
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Location is where a pull spec was found.
+type Location struct {
+	// File is the path of the file holding the pull spec.
+	File string `json:"file"`
+	// Path is the path of the value holding the pull spec in its document, like
+	// spec.install.spec.deployments[0].spec.template.spec.containers[0].image.
+	Path string `json:"path"`
+	// Line and Column are the position of the value in the file, counting from 1.
+	// They are 0 when the document wasn't read from a file.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+// String returns a string representation of the location.
+func (location Location) String() string {
+	if location.Line == 0 {
+		return fmt.Sprintf("%s: %s", location.File, location.Path)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", location.File, location.Line, location.Column, location.Path)
+}
+
+// locatable is implemented by the pull specs that can be given a location.
+type locatable interface {
+	imagePath() []interface{}
+	setLocation(Location)
+}
+
+// mapID identifies a map by the data it points to.
+func mapID(m map[string]interface{}) uintptr {
+	return reflect.ValueOf(m).Pointer()
+}
+
+// pathIndex returns the path of every map of the document.
+func (doc *document) pathIndex() map[uintptr][]interface{} {
+	paths := map[uintptr][]interface{}{}
+	indexPaths(doc.data.Object, []interface{}{}, paths)
+	return paths
+}
+
+// locate sets the location of each pull spec whose data is held by the
+// document. Pull specs that already have a location are left untouched.
+func (doc *document) locate(pullspecs []NamedPullSpec, paths map[uintptr][]interface{}) {
+	for _, ps := range pullspecs {
+		l, ok := ps.(locatable)
+
+		if !ok || ps.Location().File != "" {
+			continue
+		}
+
+		path, ok := paths[mapID(ps.Data())]
+
+		if !ok {
+			continue
+		}
+
+		path = append(path[:len(path):len(path)], l.imagePath()...)
+		location := Location{File: doc.path, Path: formatPath(path)}
+
+		if node := doc.findNode(path); node != nil {
+			location.Line = node.Line + doc.line
+			location.Column = node.Column
+		}
+
+		l.setLocation(location)
+	}
+}
+
+// indexPaths records the path of every map found under value.
+func indexPaths(value interface{}, path []interface{}, paths map[uintptr][]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		paths[mapID(v)] = path
+
+		for key, child := range v {
+			indexPaths(child, append(path[:len(path):len(path)], key), paths)
+		}
+	case []interface{}:
+		for i, child := range v {
+			indexPaths(child, append(path[:len(path):len(path)], i), paths)
+		}
+	}
+}
+
+var plainKey = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_\-]*$`)
+
+// formatPath formats path like spec.containers[0].image, keys that aren't
+// plain names are quoted like metadata.annotations["example.com/image"].
+func formatPath(path []interface{}) string {
+	b := strings.Builder{}
+
+	for _, segment := range path {
+		switch s := segment.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+		case string:
+			if !plainKey.MatchString(s) {
+				fmt.Fprintf(&b, "[%s]", strconv.Quote(s))
+				continue
+			}
+
+			if b.Len() != 0 {
+				b.WriteByte('.')
+			}
+
+			b.WriteString(s)
+		}
+	}
+
+	return b.String()
+}
+
+// findNode returns the yaml node of the value at path in the document text, or
+// nil if the path isn't in the text.
+func (doc *document) findNode(path []interface{}) *yamlv3.Node {
+	if doc.raw == nil {
+		return nil
+	}
+
+	if doc.node == nil {
+		root := &yamlv3.Node{}
+
+		if err := yamlv3.Unmarshal(doc.raw, root); err != nil || len(root.Content) != 1 {
+			return nil
+		}
+
+		doc.node = root.Content[0]
+	}
+
+	node := doc.node
+
+	for _, segment := range path {
+		var next *yamlv3.Node
+
+		switch s := segment.(type) {
+		case int:
+			if node.Kind == yamlv3.SequenceNode && s < len(node.Content) {
+				next = node.Content[s]
+			}
+		case string:
+			if node.Kind == yamlv3.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == s {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		node = next
+	}
+
+	return node
+}
+
+// ownable is implemented by the pull specs that can be given an owner.
+type ownable interface {
+	ownerData() map[string]interface{}
+	setOwner(Owner)
+}
+
+// setOwners sets the owner of each pull spec held by a workload, ownerOf
+// returns the owner of the value at a path of the document.
+func setOwners(pullspecs []NamedPullSpec, paths map[uintptr][]interface{}, ownerOf func([]interface{}) Owner) {
+	for _, ps := range pullspecs {
+		o, ok := ps.(ownable)
+
+		if !ok {
+			continue
+		}
+
+		if path, ok := paths[mapID(o.ownerData())]; ok {
+			o.setOwner(ownerOf(path))
+		}
+	}
+}
+
+// containerName returns the name of the container holding the value at path
+// in root, if any.
+func containerName(root interface{}, path []interface{}) string {
+	value := root
+
+	for i, segment := range path {
+		switch s := segment.(type) {
+		case int:
+			slice, ok := value.([]interface{})
+			if !ok || s >= len(slice) {
+				return ""
+			}
+
+			value = slice[s]
+
+			if i == 0 {
+				continue
+			}
+
+			if key, ok := path[i-1].(string); ok && (key == "containers" || key == "initContainers") {
+				container, _ := value.(map[string]interface{})
+				name, _ := container["name"].(string)
+				return name
+			}
+		case string:
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+
+			value = m[s]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,90 @@
+package pullspec
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Location", func() {
+	DescribeTable("formatPath",
+		func(path []interface{}, expected string) {
+			Expect(formatPath(path)).To(Equal(expected))
+		},
+		Entry("keys", []interface{}{"spec", "image"}, "spec.image"),
+		Entry("indexes", []interface{}{"spec", "containers", 0, "image"}, "spec.containers[0].image"),
+		Entry("quoted keys", []interface{}{"metadata", "annotations", "example.com/image"}, `metadata.annotations["example.com/image"]`),
+		Entry("quoted first key", []interface{}{"a.b"}, `["a.b"]`),
+	)
+
+	It("should locate the pullspecs of a file", func() {
+		dir, err := os.MkdirTemp("", "location")
+		Expect(err).To(Succeed())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "csv.yaml")
+		Expect(os.WriteFile(path, []byte(`kind: ConfigMap
+metadata:
+  name: images
+data:
+  operand: registry.example.com/operand:1.0
+---
+kind: ClusterServiceVersion
+metadata:
+  name: csv
+  annotations:
+    example.com/image: registry.example.com/foo:1
+spec:
+  install:
+    spec:
+      deployments:
+      - spec:
+          template:
+            spec:
+              containers:
+              - name: c1
+                image: registry.example.com/foo:1
+                env:
+                - name: RELATED_IMAGE_OPERAND
+                  valueFrom:
+                    configMapKeyRef:
+                      name: images
+                      key: operand
+`), 0600)).To(Succeed())
+
+		csv, err := NewOperatorCSVFromFile(path, nil)
+		Expect(err).To(Succeed())
+
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		locations := map[PullSpecKind]Location{}
+		for _, ps := range pullspecs {
+			locations[ps.Kind()] = ps.Location()
+		}
+
+		Expect(locations).To(Equal(map[PullSpecKind]Location{
+			KindContainer: {
+				File:   path,
+				Path:   "spec.install.spec.deployments[0].spec.template.spec.containers[0].image",
+				Line:   21,
+				Column: 24,
+			},
+			KindRelatedImageEnv: {
+				File:   path,
+				Path:   "data.operand",
+				Line:   5,
+				Column: 12,
+			},
+			KindAnnotation: {
+				File:   path,
+				Path:   `metadata.annotations["example.com/image"]`,
+				Line:   11,
+				Column: 24,
+			},
+		}))
+	})
+})
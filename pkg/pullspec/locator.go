@@ -0,0 +1,305 @@
+package pullspec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Locator finds pull specs in a ClusterServiceVersion. Locators are registered
+// on the OperatorCSV, see AddLocator.
+type Locator interface {
+	// Name identifies the locator, it's used to disable it.
+	Name() string
+	// Locate returns the pull specs found in the CSV.
+	Locate(csv *OperatorCSV) ([]NamedPullSpec, error)
+}
+
+// The names of the built-in locators.
+const (
+	// LocatorRelatedImages finds the CSV relatedImages.
+	LocatorRelatedImages = "relatedImages"
+	// LocatorContainers finds the containers of the CSV deployments.
+	LocatorContainers = "containers"
+	// LocatorInitContainers finds the init containers of the CSV deployments.
+	LocatorInitContainers = "initContainers"
+	// LocatorImageVolumes finds the image volumes of the CSV deployments.
+	LocatorImageVolumes = "imageVolumes"
+	// LocatorRelatedImageEnvs finds the RELATED_IMAGE_ env vars of the
+	// containers and init containers.
+	LocatorRelatedImageEnvs = "relatedImageEnvs"
+	// LocatorAnnotations finds images in the annotations with a known key, see
+	// SetAnnotationKeys.
+	LocatorAnnotations = "annotations"
+	// LocatorGuessedAnnotations finds images in every annotation with the
+	// heuristic.
+	LocatorGuessedAnnotations = "guessedAnnotations"
+)
+
+// locatorFunc is a Locator built from a function.
+type locatorFunc struct {
+	name   string
+	locate func(csv *OperatorCSV) ([]NamedPullSpec, error)
+}
+
+func (locator *locatorFunc) Name() string {
+	return locator.name
+}
+
+func (locator *locatorFunc) Locate(csv *OperatorCSV) ([]NamedPullSpec, error) {
+	return locator.locate(csv)
+}
+
+// builtinLocators returns the locators registered on every OperatorCSV, in the
+// order their pull specs are returned.
+func builtinLocators() []Locator {
+	return []Locator{
+		&locatorFunc{LocatorRelatedImages, (*OperatorCSV).relatedImagePullSpecs},
+		&locatorFunc{LocatorContainers, (*OperatorCSV).containerPullSpecs},
+		&locatorFunc{LocatorInitContainers, (*OperatorCSV).initContainerPullSpecs},
+		&locatorFunc{LocatorImageVolumes, (*OperatorCSV).imageVolumePullSpecs},
+		&locatorFunc{LocatorRelatedImageEnvs, (*OperatorCSV).relatedImageEnvPullSpecs},
+		&locatorFunc{LocatorAnnotations, func(csv *OperatorCSV) ([]NamedPullSpec, error) {
+			return csv.annotationPullSpecs(csv.annotationKeys)
+		}},
+		&locatorFunc{LocatorGuessedAnnotations, func(csv *OperatorCSV) ([]NamedPullSpec, error) {
+			return csv.annotationPullSpecs(nil)
+		}},
+	}
+}
+
+// Locators returns the locators of the CSV, in the order they're run.
+func (csv *OperatorCSV) Locators() []Locator {
+	return append([]Locator{}, csv.locators...)
+}
+
+// AddLocator registers a locator on the CSV. It runs after the locators
+// already registered. A locator with the same name is replaced.
+func (csv *OperatorCSV) AddLocator(locator Locator) {
+	defer csv.invalidate()
+
+	for i := range csv.locators {
+		if csv.locators[i].Name() == locator.Name() {
+			csv.locators[i] = locator
+			return
+		}
+	}
+
+	csv.locators = append(csv.locators, locator)
+}
+
+// DisableLocator removes the locator named name from the CSV, its pull specs
+// are no longer extracted, replaced nor added to the relatedImages.
+func (csv *OperatorCSV) DisableLocator(name string) error {
+	for i := range csv.locators {
+		if csv.locators[i].Name() == name {
+			csv.locators = append(csv.locators[:i:i], csv.locators[i+1:]...)
+			csv.invalidate()
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown locator %q", name)
+}
+
+// SetAnnotationKeys sets the annotation keys holding images. By default only
+// the containerImage annotation is known.
+func (csv *OperatorCSV) SetAnnotationKeys(keys ...string) {
+	csv.annotationKeys = append(stringSlice{}, keys...)
+	csv.invalidate()
+}
+
+// KindPath is an image found by a PathLocator.
+const KindPath PullSpecKind = "path"
+
+// PathLocator finds the images at a path of the CSV, like
+// spec.install.spec.deployments[*].spec.template.spec.volumes[*].image.reference.
+// Segments are keys, quoted keys like ["example.com/image"], indexes like [0],
+// [*] to match every item of a list or [?key=value] to match the items whose
+// key holds value. The path must end with a key.
+type PathLocator struct {
+	name string
+	// parents finds the maps holding the images under key.
+	parents func(interface{}) ([]interface{}, error)
+	key     string
+	kind    PullSpecKind
+}
+
+// NewPathLocator returns a locator finding the images at path. Its pull specs
+// have the given kind, or KindPath if kind is empty.
+func NewPathLocator(name, path string, kind PullSpecKind) (*PathLocator, error) {
+	parents, key, err := utils.ParseParentPath(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("locator %s: %w", name, err)
+	}
+
+	if kind == "" {
+		kind = KindPath
+	}
+
+	return &PathLocator{name: name, parents: parents.All, key: key, kind: kind}, nil
+}
+
+// Name returns the name of the locator.
+func (locator *PathLocator) Name() string {
+	return locator.name
+}
+
+// Locate returns a pull spec for each string found at the path.
+func (locator *PathLocator) Locate(csv *OperatorCSV) ([]NamedPullSpec, error) {
+	pullspecs := []NamedPullSpec{}
+	parents, err := locator.parents(csv.data.Object)
+
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return pullspecs, nil
+		}
+
+		return nil, err
+	}
+
+	for _, parent := range parents {
+		data, ok := parent.(map[string]interface{})
+
+		if image, isString := data[locator.key].(string); ok && isString && image != "" {
+			pullspecs = append(pullspecs, &PathPullSpec{
+				namedPullSpec: namedPullSpec{imageKey: locator.key, data: data},
+				locator:       locator.name,
+				kind:          locator.kind,
+			})
+		}
+	}
+
+	return pullspecs, nil
+}
+
+// PathPullSpec is an image found by a PathLocator.
+type PathPullSpec struct {
+	namedPullSpec
+	locator string
+	kind    PullSpecKind
+}
+
+// Kind returns the kind of the pullspec.
+func (ps *PathPullSpec) Kind() PullSpecKind {
+	return ps.kind
+}
+
+// Name returns the name of the pullspec, the locator name followed by the
+// image repository.
+func (ps *PathPullSpec) Name() string {
+	return fmt.Sprintf("%s-%s", ps.locator, imagename.Parse(ps.Image()).Repo)
+}
+
+// String returns a string representation of the pullspec.
+func (ps *PathPullSpec) String() string {
+	return fmt.Sprintf("%s %s", ps.locator, ps.Name())
+}
+
+// AsYamlObject returns the pullspec as a map[string]interface{}.
+func (ps *PathPullSpec) AsYamlObject() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  ps.Name(),
+		"image": ps.Image(),
+	}
+}
+
+// LocatorConfig configures the locators of the CSVs. It is usually read from a
+// file with LoadLocatorConfig.
+type LocatorConfig struct {
+	// AnnotationKeys are the annotation keys holding images. The built-in
+	// containerImage key is kept if this is empty.
+	AnnotationKeys []string `json:"annotationKeys,omitempty" yaml:"annotationKeys,omitempty"`
+	// Disable lists the names of the locators to turn off.
+	Disable []string `json:"disable,omitempty" yaml:"disable,omitempty"`
+	// Locators are the extra locations holding images.
+	Locators []PathLocatorConfig `json:"locators,omitempty" yaml:"locators,omitempty"`
+	// ContainerArgPattern enables the containerArgs locator, it matches the
+	// names of the flags holding images like image$.
+	ContainerArgPattern string `json:"containerArgPattern,omitempty" yaml:"containerArgPattern,omitempty"`
+	// ImageEnvPattern enables the imageEnvs locator, it matches the names of
+	// the env vars holding images like _IMAGE$.
+	ImageEnvPattern string `json:"imageEnvPattern,omitempty" yaml:"imageEnvPattern,omitempty"`
+}
+
+// PathLocatorConfig configures a PathLocator.
+type PathLocatorConfig struct {
+	Name string       `json:"name" yaml:"name"`
+	Path string       `json:"path" yaml:"path"`
+	Kind PullSpecKind `json:"kind,omitempty" yaml:"kind,omitempty"`
+}
+
+// LoadLocatorConfig reads a LocatorConfig from a yaml or json file.
+func LoadLocatorConfig(path string) (*LocatorConfig, error) {
+	b, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := &LocatorConfig{}
+	dec := yamlv3.NewDecoder(strings.NewReader(string(b)))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(config); err != nil {
+		return nil, utils.NewError(err, "%s: invalid locator config: %v", path, err)
+	}
+
+	return config, nil
+}
+
+// Apply registers the configured locators on the CSV.
+func (config *LocatorConfig) Apply(csv *OperatorCSV) error {
+	if len(config.AnnotationKeys) != 0 {
+		csv.SetAnnotationKeys(config.AnnotationKeys...)
+	}
+
+	if config.ContainerArgPattern != "" {
+		pattern, err := regexp.Compile(config.ContainerArgPattern)
+
+		if err != nil {
+			return fmt.Errorf("invalid containerArgPattern: %w", err)
+		}
+
+		csv.AddLocator(NewContainerArgLocator(pattern))
+	}
+
+	if config.ImageEnvPattern != "" {
+		pattern, err := regexp.Compile(config.ImageEnvPattern)
+
+		if err != nil {
+			return fmt.Errorf("invalid imageEnvPattern: %w", err)
+		}
+
+		csv.AddLocator(NewImageEnvLocator(pattern))
+	}
+
+	for _, locatorConfig := range config.Locators {
+		if locatorConfig.Name == "" {
+			return fmt.Errorf("locator %q: missing name", locatorConfig.Path)
+		}
+
+		locator, err := NewPathLocator(locatorConfig.Name, locatorConfig.Path, locatorConfig.Kind)
+
+		if err != nil {
+			return err
+		}
+
+		csv.AddLocator(locator)
+	}
+
+	for _, name := range config.Disable {
+		if err := csv.DisableLocator(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
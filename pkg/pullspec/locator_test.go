@@ -0,0 +1,150 @@
+package pullspec
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Locators", func() {
+	const src = `kind: ClusterServiceVersion
+metadata:
+  annotations:
+    containerImage: registry.example.com/team/operator:1
+    example.com/image: registry.example.com/team/custom:1
+spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+              volumes:
+              - name: model
+                image:
+                  reference: registry.example.com/team/model:1
+                  pullPolicy: IfNotPresent
+              - name: config
+                configMap:
+                  name: config
+`
+
+	var csv *OperatorCSV
+
+	BeforeEach(func() {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err = NewOperatorCSV("csv.yaml", data, nil)
+		Expect(err).To(Succeed())
+	})
+
+	kinds := func() map[string]PullSpecKind {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		result := map[string]PullSpecKind{}
+		for _, ps := range pullspecs {
+			result[ps.Image()] = ps.Kind()
+		}
+		return result
+	}
+
+	It("should register the built-in locators", func() {
+		names := []string{}
+		for _, locator := range csv.Locators() {
+			names = append(names, locator.Name())
+		}
+
+		Expect(names).To(Equal([]string{
+			LocatorRelatedImages, LocatorContainers, LocatorInitContainers,
+			LocatorImageVolumes, LocatorRelatedImageEnvs, LocatorAnnotations, LocatorGuessedAnnotations,
+		}))
+	})
+
+	It("should find images with a path locator", func() {
+		locator, err := NewPathLocator("model", "spec.install.spec.deployments[*].spec.template.spec.volumes[*].image.reference", "")
+		Expect(err).To(Succeed())
+		csv.AddLocator(locator)
+
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		ps := pullspecs[len(pullspecs)-1]
+		Expect(ps.Image()).To(Equal("registry.example.com/team/model:1"))
+		Expect(ps.Kind()).To(Equal(KindPath))
+		Expect(ps.Name()).To(Equal("model-model"))
+		Expect(ps.Owner()).To(Equal(Owner{Deployment: "operator"}))
+		Expect(ps.Location().Path).To(Equal("spec.install.spec.deployments[0].spec.template.spec.volumes[0].image.reference"))
+
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("registry.example.com/team/model:1"): *imagename.Parse("registry.example.com/team/model@sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+		})).To(Succeed())
+		Expect(ps.Image()).To(Equal("registry.example.com/team/model@sha256:1111111111111111111111111111111111111111111111111111111111111111"))
+		Expect(ps.Data()["pullPolicy"]).To(Equal("IfNotPresent"))
+	})
+
+	It("should disable locators", func() {
+		Expect(csv.DisableLocator(LocatorGuessedAnnotations)).To(Succeed())
+		Expect(csv.DisableLocator(LocatorContainers)).To(Succeed())
+		Expect(kinds()).To(Equal(map[string]PullSpecKind{
+			"registry.example.com/team/operator:1": KindAnnotation,
+			"registry.example.com/team/model:1":    KindImageVolume,
+		}))
+
+		Expect(csv.DisableLocator("other")).To(MatchError(`unknown locator "other"`))
+	})
+
+	It("should filter items with a path locator", func() {
+		locator, err := NewPathLocator("manager", "spec.install.spec.deployments[*].spec.template.spec.containers[?name=manager].image", "")
+		Expect(err).To(Succeed())
+		Expect(csv.DisableLocator(LocatorContainers)).To(Succeed())
+		csv.AddLocator(locator)
+		Expect(kinds()).To(HaveKeyWithValue("registry.example.com/team/operator:1", KindPath))
+
+		_, err = NewPathLocator("invalid", "spec.containers[0]", "")
+		Expect(err).To(MatchError(ContainSubstring("must end with a key")))
+	})
+
+	It("should apply a locator config", func() {
+		dir, err := os.MkdirTemp("", "locator")
+		Expect(err).To(Succeed())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "locators.yaml")
+		Expect(os.WriteFile(path, []byte(`annotationKeys:
+- example.com/image
+disable:
+- containers
+- guessedAnnotations
+locators:
+- name: model
+  path: spec.install.spec.deployments[0].spec.template.spec.volumes[*].image.reference
+  kind: imageVolume
+`), 0600)).To(Succeed())
+
+		config, err := LoadLocatorConfig(path)
+		Expect(err).To(Succeed())
+		Expect(config.Apply(csv)).To(Succeed())
+
+		Expect(kinds()).To(Equal(map[string]PullSpecKind{
+			"registry.example.com/team/custom:1": KindAnnotation,
+			"registry.example.com/team/model:1":  PullSpecKind("imageVolume"),
+		}))
+
+		Expect(os.WriteFile(path, []byte("other: 1\n"), 0600)).To(Succeed())
+		_, err = LoadLocatorConfig(path)
+		Expect(err).To(HaveOccurred())
+	})
+})
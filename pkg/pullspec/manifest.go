@@ -0,0 +1,288 @@
+package pullspec
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Manifest represents a kubernetes object of a bundle other than the
+// ClusterServiceVersion, like a CustomResourceDefinition, a ConfigMap or a
+// Deployment.
+type Manifest struct {
+	*document
+}
+
+// NewManifest creates a Manifest using the data provided via an unstructured kubernetes object.
+func NewManifest(path string, data *unstructured.Unstructured, pullSpecHeuristic Heuristic) *Manifest {
+	return newManifest(&document{data: *data, path: path}, pullSpecHeuristic)
+}
+
+func newManifest(doc *document, pullSpecHeuristic Heuristic) *Manifest {
+	if pullSpecHeuristic == nil {
+		pullSpecHeuristic = DefaultHeuristic
+	}
+
+	doc.pullspecHeuristic = pullSpecHeuristic
+
+	return &Manifest{document: doc}
+}
+
+// String returns a string representation of the manifest.
+func (manifest *Manifest) String() string {
+	return fmt.Sprintf("%s %s", strings.ToLower(manifest.data.GetKind()), manifest.data.GetName())
+}
+
+// GetPullSpecs will return a list of all the images found in the manifest, as
+// imagename.Parse parses them. A malformed image returns an error.
+func (manifest *Manifest) GetPullSpecs() ([]*imagename.ImageName, error) {
+	namedList, err := manifest.NamedPullSpecs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[imagename.ImageName]bool)
+	imageList := make([]*imagename.ImageName, 0, len(namedList))
+
+	for _, ps := range namedList {
+		log.Printf("Found pullspec for %s: %s", ps.String(), ps.Image())
+		if parsed, err := manifest.parseImage(ps); err != nil || parsed == nil {
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		image := imagename.Parse(ps.Image())
+
+		if seen[*image] {
+			continue
+		}
+
+		seen[*image] = true
+		imageList = append(imageList, image)
+	}
+
+	return imageList, nil
+}
+
+// ReplacePullSpecs will replace each pullspec found with the provide image,
+// equivalent references match the same replacement. A malformed image returns
+// an error.
+func (manifest *Manifest) ReplacePullSpecs(replacement map[imagename.ImageName]imagename.ImageName) error {
+	pullspecs, err := manifest.NamedPullSpecs()
+	if err != nil {
+		return err
+	}
+
+	index, err := newReplacementIndex(replacement)
+	if err != nil {
+		return err
+	}
+
+	for _, pullspec := range pullspecs {
+		parsed, err := manifest.parseImage(pullspec)
+
+		if err != nil {
+			return err
+		}
+
+		if parsed == nil {
+			continue
+		}
+
+		old, new, ok := index.lookup(parsed)
+
+		if ok && old != new && pullspec.Image() != new.String() {
+			log.Printf("%s - Replaced pullspec for %s: %s -> %s", manifest.path, pullspec.String(), old, new)
+			pullspec.SetImage(new.String())
+		}
+	}
+
+	return nil
+}
+
+var (
+	podTemplateSpecLens = utils.Lens().M("spec").M("template").M("spec").Build()
+	cronJobPodSpecLens  = utils.Lens().M("spec").M("jobTemplate").M("spec").M("template").M("spec").Build()
+	podSpecLens         = utils.Lens().M("spec").Build()
+
+	// podSpecLenses finds the pod spec of the workload kinds.
+	podSpecLenses = map[string]func(interface{}) (map[string]interface{}, error){
+		"Pod":                   podSpecLens.M,
+		"Deployment":            podTemplateSpecLens.M,
+		"DaemonSet":             podTemplateSpecLens.M,
+		"StatefulSet":           podTemplateSpecLens.M,
+		"ReplicaSet":            podTemplateSpecLens.M,
+		"ReplicationController": podTemplateSpecLens.M,
+		"Job":                   podTemplateSpecLens.M,
+		"CronJob":               cronJobPodSpecLens.M,
+	}
+)
+
+// NamedPullSpecs returns the containers of workload manifests and the pullspecs
+// the heuristic finds in the other strings of the manifest, within its scope.
+func (manifest *Manifest) NamedPullSpecs() ([]NamedPullSpec, error) {
+	pullspecs := []NamedPullSpec{}
+
+	containers, err := manifest.containerPullSpecs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// strings already holding a container image are not guessed again
+	claimed := map[uintptr]bool{}
+
+	for _, container := range containers {
+		claimed[mapID(container.Data())] = true
+		pullspecs = append(pullspecs, container)
+	}
+
+	switch manifest.Scope() {
+	case ScopeEverywhere:
+		manifest.findPotentialPullSpecs(manifest.data.Object, claimed, &pullspecs)
+	case ScopeAnnotations:
+		if annotations, err := annotations.M(manifest.data.Object); err == nil {
+			manifest.findPotentialPullSpecs(annotations, claimed, &pullspecs)
+		}
+	}
+
+	paths := manifest.pathIndex()
+	pullspecs = manifest.excluded(pullspecs, paths)
+	manifest.locate(pullspecs, paths)
+	setOwners(pullspecs, paths, manifest.ownerOf)
+
+	for i := range pullspecs {
+		pullspecs[i] = &manifestPullSpec{NamedPullSpec: pullspecs[i], manifest: manifest}
+	}
+
+	return pullspecs, nil
+}
+
+// ownerOf returns the workload and container holding the value at path.
+func (manifest *Manifest) ownerOf(path []interface{}) Owner {
+	if _, ok := podSpecLenses[manifest.data.GetKind()]; !ok {
+		return Owner{}
+	}
+
+	return Owner{
+		Deployment: manifest.data.GetName(),
+		Container:  containerName(manifest.data.Object, path),
+	}
+}
+
+func (manifest *Manifest) containerPullSpecs() ([]NamedPullSpec, error) {
+	findPodSpec, ok := podSpecLenses[manifest.data.GetKind()]
+
+	if !ok {
+		return nil, nil
+	}
+
+	podSpec, err := findPodSpec(manifest.data.Object)
+
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	pullspecs := []NamedPullSpec{}
+
+	for _, key := range []string{"containers", "initContainers"} {
+		containers, ok := podSpec[key].([]interface{})
+
+		if !ok {
+			continue
+		}
+
+		for i := range containers {
+			var (
+				pullspec NamedPullSpec
+				err      error
+			)
+
+			if key == "containers" {
+				pullspec, err = NewContainer(containers[i])
+			} else {
+				pullspec, err = NewInitContainer(containers[i])
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			pullspecs = append(pullspecs, pullspec)
+		}
+	}
+
+	volumes, _ := podSpec["volumes"].([]interface{})
+	imageVolumePullSpecs, err := imageVolumes(volumes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(pullspecs, imageVolumePullSpecs...), nil
+}
+
+func (manifest *Manifest) findPotentialPullSpecs(root map[string]interface{}, claimed map[uintptr]bool, specs *[]NamedPullSpec) {
+	for _, key := range sortedKeys(root) {
+		switch val := root[key].(type) {
+		case string:
+			if key == "image" && claimed[mapID(root)] {
+				continue
+			}
+
+			results := manifest.findPullSpecs(val)
+
+			// last first, so replacing an image doesn't move the next ones
+			for j := len(results) - 1; j >= 0; j-- {
+				ii, jj := results[j][0], results[j][1]
+				*specs = append(*specs, NewAnnotation(root, key, ii, jj))
+			}
+		case map[string]interface{}:
+			manifest.findPotentialPullSpecs(val, claimed, specs)
+		case []interface{}:
+			for i := range val {
+				if datav, ok := val[i].(map[string]interface{}); ok {
+					manifest.findPotentialPullSpecs(datav, claimed, specs)
+				}
+			}
+		}
+	}
+}
+
+// manifestPullSpec is a pullspec found in a Manifest. Its name is prefixed with
+// the manifest name so it can't clash with the names of the CSV pullspecs.
+type manifestPullSpec struct {
+	NamedPullSpec
+	manifest *Manifest
+}
+
+// Name returns the name of the pullspec.
+func (ps *manifestPullSpec) Name() string {
+	return fmt.Sprintf("%s-%s", ps.manifest.data.GetName(), ps.NamedPullSpec.Name())
+}
+
+// String returns a string representation of the pullspec.
+func (ps *manifestPullSpec) String() string {
+	return fmt.Sprintf("%s %s", ps.manifest.String(), ps.NamedPullSpec.String())
+}
+
+// AsYamlObject returns the pullspec as a map[string]interface{}.
+func (ps *manifestPullSpec) AsYamlObject() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  ps.Name(),
+		"image": ps.Image(),
+	}
+}
@@ -0,0 +1,139 @@
+package pullspec
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+// replacementIndex looks up the replacements of images, equivalent references
+// like nginx:1 and docker.io/library/nginx:1 match the same replacement, see
+// imagename.ImageName.Canonical.
+type replacementIndex struct {
+	replacement map[imagename.ImageName]imagename.ImageName
+	// canonical maps the canonical form of the replaced images to their key.
+	canonical map[imagename.ImageName]imagename.ImageName
+}
+
+// newReplacementIndex indexes the replacements. Equivalent images replaced by
+// images that aren't equivalent return an error.
+func newReplacementIndex(replacement map[imagename.ImageName]imagename.ImageName) (*replacementIndex, error) {
+	index := &replacementIndex{
+		replacement: replacement,
+		canonical:   make(map[imagename.ImageName]imagename.ImageName, len(replacement)),
+	}
+
+	for key, new := range replacement {
+		canonical := key.Canonical()
+		other, found := index.canonical[canonical]
+
+		if found {
+			otherNew := replacement[other]
+
+			if !new.Equivalent(&otherNew) {
+				first, second := new.String(), otherNew.String()
+
+				if second < first {
+					first, second = second, first
+				}
+
+				return nil, fmt.Errorf("conflicting replacements for %s: %s and %s", canonical.String(), first, second)
+			}
+
+			// Keep the same key whatever the order of the map.
+			if other.String() < key.String() {
+				continue
+			}
+		}
+
+		index.canonical[canonical] = key
+	}
+
+	return index, nil
+}
+
+// lookup returns the key and the replacement of the image, the exact image
+// first, else an equivalent one.
+func (index *replacementIndex) lookup(image *imagename.ImageName) (imagename.ImageName, imagename.ImageName, bool) {
+	if new, ok := index.replacement[*image]; ok {
+		return *image, new, true
+	}
+
+	key, ok := index.canonical[image.Canonical()]
+
+	if !ok {
+		return imagename.ImageName{}, imagename.ImageName{}, false
+	}
+
+	return key, index.replacement[key], true
+}
+
+// normalizedReplacements maps the images of the pull specs to their canonical
+// form. Malformed images are left out, replacing reports them.
+func normalizedReplacements(pullspecs []NamedPullSpec) map[imagename.ImageName]imagename.ImageName {
+	replacement := map[imagename.ImageName]imagename.ImageName{}
+
+	for _, ps := range pullspecs {
+		image, err := imagename.ParseStrict(ps.Image())
+
+		if err != nil {
+			continue
+		}
+
+		replacement[*image] = image.Canonical()
+	}
+
+	return replacement
+}
+
+// NormalizePullSpecs writes the canonical form of the images of the CSV, like
+// docker.io/library/nginx:latest for nginx, see imagename.ImageName.Canonical.
+// Skipped images and strings out of the scope of the CSV are left alone.
+func (csv *OperatorCSV) NormalizePullSpecs() error {
+	pullspecs, err := csv.everywherePullSpecs()
+
+	if err != nil {
+		return err
+	}
+
+	return csv.ReplacePullSpecsEverywhere(normalizedReplacements(pullspecs))
+}
+
+// NormalizePullSpecs writes the canonical form of the images of the manifest.
+func (manifest *Manifest) NormalizePullSpecs() error {
+	pullspecs, err := manifest.NamedPullSpecs()
+
+	if err != nil {
+		return err
+	}
+
+	return manifest.ReplacePullSpecs(normalizedReplacements(pullspecs))
+}
+
+// NormalizePullSpecs writes the canonical form of the images of the CSVs and
+// of the other manifests of the bundle. Skipped images are left alone.
+func (bundle *Bundle) NormalizePullSpecs() error {
+	pullspecs := []NamedPullSpec{}
+
+	for _, csv := range bundle.CSVs {
+		found, err := csv.everywherePullSpecs()
+
+		if err != nil {
+			return err
+		}
+
+		pullspecs = append(pullspecs, found...)
+	}
+
+	for _, manifest := range bundle.Manifests {
+		found, err := manifest.NamedPullSpecs()
+
+		if err != nil {
+			return err
+		}
+
+		pullspecs = append(pullspecs, found...)
+	}
+
+	return bundle.ReplacePullSpecs(normalizedReplacements(pullspecs))
+}
@@ -0,0 +1,87 @@
+package pullspec
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+var _ = Describe("Equivalent references", func() {
+	const src = `kind: ClusterServiceVersion
+metadata:
+  annotations:
+    containerImage: docker.io/library/nginx:1.25
+    pinning.operatorframework.io/skip-images: quay.io/team/must-gather:*
+spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: nginx:1.25
+              - name: gather
+                image: quay.io/team/must-gather:1
+              - name: agent
+                image: team/agent
+`
+
+	var csv *OperatorCSV
+
+	BeforeEach(func() {
+		var err error
+		csv, err = decodeCSV(src)
+		Expect(err).To(Succeed())
+	})
+
+	images := func() []string {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		result := []string{}
+		for _, ps := range pullspecs {
+			result = append(result, ps.Image())
+		}
+		return result
+	}
+
+	It("should match the same replacement", func() {
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("docker.io/nginx:1.25"):        *imagename.Parse("nginx:1.26"),
+			*imagename.Parse("docker.io/team/agent:latest"): *imagename.Parse("team/agent:1"),
+		})).To(Succeed())
+
+		Expect(images()).To(ConsistOf("nginx:1.26", "quay.io/team/must-gather:1", "team/agent:1"))
+		Expect(csv.data.GetAnnotations()["containerImage"]).To(Equal("nginx:1.26"))
+	})
+
+	It("should prefer the exact replacement", func() {
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("nginx:1.25"):                   *imagename.Parse("nginx:1.26"),
+			*imagename.Parse("docker.io/library/nginx:1.25"): *imagename.Parse("docker.io/library/nginx:1.26"),
+		})).To(Succeed())
+
+		Expect(images()).To(ContainElements("nginx:1.26", "docker.io/library/nginx:1.26"))
+	})
+
+	It("should reject conflicting replacements", func() {
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("nginx:1.25"):                   *imagename.Parse("nginx:1.26"),
+			*imagename.Parse("docker.io/library/nginx:1.25"): *imagename.Parse("nginx:1.27"),
+		})).To(MatchError("conflicting replacements for docker.io/library/nginx:1.25: nginx:1.26 and nginx:1.27"))
+	})
+
+	It("should normalize the images", func() {
+		Expect(csv.NormalizePullSpecs()).To(Succeed())
+
+		Expect(images()).To(ConsistOf(
+			"docker.io/library/nginx:1.25",
+			"docker.io/library/nginx:1.25",
+			"quay.io/team/must-gather:1",
+			"docker.io/team/agent:latest",
+		))
+	})
+})
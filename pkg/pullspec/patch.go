@@ -0,0 +1,852 @@
+package pullspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// errCannotPatch is returned when a change can't be expressed as an edit of
+// the original document and the caller has to re-encode it instead.
+var errCannotPatch = errors.New("change can not be patched into the original document")
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       []byte
+}
+
+// patcher applies the differences between two decoded versions of a yaml
+// document as byte-range edits on the original source, so comments, key order,
+// quoting and line wrapping of everything that didn't change are preserved.
+type patcher struct {
+	src        []byte
+	lineStarts []int
+	indentless bool
+	// newline is the line break of the source, the rendered values use it too.
+	newline string
+	edits   []edit
+}
+
+// patchYaml returns src with the changes between original and modified applied.
+// original must be the decoded form of src.
+func patchYaml(src []byte, original, modified map[string]interface{}) ([]byte, error) {
+	if reflect.DeepEqual(original, modified) {
+		return src, nil
+	}
+
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(src, doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) != 1 {
+		return nil, errCannotPatch
+	}
+
+	p := &patcher{src: src, lineStarts: lineStarts(src), newline: "\n"}
+	if bytes.Contains(src, []byte("\r\n")) {
+		p.newline = "\r\n"
+	}
+
+	p.indentless = isIndentless(doc.Content[0])
+
+	if err := p.diff(doc.Content[0], nil, original, modified, false); err != nil {
+		return nil, err
+	}
+
+	return p.apply()
+}
+
+// diff compares the original and modified values of node and records the edits
+// needed. key is the mapping key holding node, or nil if node isn't a mapping value.
+// Structural changes are only possible in block context, inside flow collections
+// (which includes JSON documents) only scalars can be rewritten.
+func (p *patcher) diff(node, key *yamlv3.Node, original, modified interface{}, flow bool) error {
+	if reflect.DeepEqual(original, modified) {
+		return nil
+	}
+
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		origMap, ok1 := original.(map[string]interface{})
+		modMap, ok2 := modified.(map[string]interface{})
+
+		if ok1 && ok2 {
+			edits := len(p.edits)
+			err := p.diffMapping(node, origMap, modMap, flow || node.Style&yamlv3.FlowStyle != 0)
+			if err == nil || flow || key == nil {
+				return err
+			}
+
+			// fall back to rendering the whole mapping again
+			p.edits = p.edits[:edits]
+		}
+	case yamlv3.SequenceNode:
+		origSlice, ok1 := original.([]interface{})
+		modSlice, ok2 := modified.([]interface{})
+
+		if ok1 && ok2 && len(origSlice) == len(modSlice) && len(node.Content) == len(origSlice) {
+			edits := len(p.edits)
+			err := p.diffSequence(node, origSlice, modSlice, flow || node.Style&yamlv3.FlowStyle != 0)
+			if err == nil || flow || key == nil {
+				return err
+			}
+
+			p.edits = p.edits[:edits]
+		}
+	case yamlv3.ScalarNode:
+		if str, ok := modified.(string); ok {
+			if _, ok := original.(string); ok {
+				if err := p.replaceScalar(node, str); err == nil {
+					return nil
+				}
+			}
+		}
+	}
+
+	if key == nil || flow {
+		return errCannotPatch
+	}
+
+	return p.replaceValue(key, node, modified)
+}
+
+func (p *patcher) diffMapping(node *yamlv3.Node, original, modified map[string]interface{}, flow bool) error {
+	seen := map[string]bool{}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+
+		if keyNode.Kind != yamlv3.ScalarNode {
+			return errCannotPatch
+		}
+
+		k := keyNode.Value
+		seen[k] = true
+
+		origValue, inOrig := original[k]
+		modValue, inMod := modified[k]
+
+		if !inOrig {
+			return errCannotPatch
+		}
+
+		if !inMod {
+			if flow {
+				return errCannotPatch
+			}
+
+			if err := p.deleteKey(keyNode, valueNode); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := p.diff(valueNode, keyNode, origValue, modValue, flow); err != nil {
+			return err
+		}
+	}
+
+	added := map[string]interface{}{}
+	for k, v := range modified {
+		if !seen[k] {
+			added[k] = v
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	if len(node.Content) == 0 || flow {
+		return errCannotPatch
+	}
+
+	indent := node.Content[0].Column - 1
+	at := p.blockEnd(node.Content[len(node.Content)-1])
+	text := &bytes.Buffer{}
+
+	if at > 0 && p.src[at-1] != '\n' {
+		text.WriteByte('\n')
+	}
+
+	if err := p.renderMapping(text, added, indent); err != nil {
+		return err
+	}
+
+	p.edits = append(p.edits, edit{start: at, end: at, text: text.Bytes()})
+	return nil
+}
+
+func (p *patcher) diffSequence(node *yamlv3.Node, original, modified []interface{}, flow bool) error {
+	for i := range node.Content {
+		if err := p.diff(node.Content[i], nil, original[i], modified[i], flow); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceScalar rewrites a scalar in place keeping its original style.
+func (p *patcher) replaceScalar(node *yamlv3.Node, value string) error {
+	start := p.offset(node.Line, node.Column)
+
+	switch node.Style {
+	case 0, yamlv3.TaggedStyle:
+		if !bytes.HasPrefix(p.src[start:], []byte(node.Value)) || strings.Contains(node.Value, "\n") {
+			return errCannotPatch
+		}
+
+		text, err := renderScalar(value)
+		if err != nil {
+			return err
+		}
+
+		p.edits = append(p.edits, edit{start: start, end: start + len(node.Value), text: []byte(text)})
+		return nil
+	case yamlv3.DoubleQuotedStyle:
+		end, err := p.quotedEnd(start, '"')
+		if err != nil {
+			return err
+		}
+
+		buff := &bytes.Buffer{}
+		enc := json.NewEncoder(buff)
+		enc.SetEscapeHTML(false)
+
+		if err := enc.Encode(value); err != nil {
+			return err
+		}
+
+		p.edits = append(p.edits, edit{start: start, end: end, text: bytes.TrimRight(buff.Bytes(), "\n")})
+		return nil
+	case yamlv3.SingleQuotedStyle:
+		if strings.Contains(value, "\n") {
+			return errCannotPatch
+		}
+
+		end, err := p.quotedEnd(start, '\'')
+		if err != nil {
+			return err
+		}
+
+		text := "'" + strings.ReplaceAll(value, "'", "''") + "'"
+		p.edits = append(p.edits, edit{start: start, end: end, text: []byte(text)})
+		return nil
+	case yamlv3.LiteralStyle:
+		return p.replaceLiteral(node, value)
+	case yamlv3.FoldedStyle:
+		return p.replaceFolded(node, value)
+	}
+
+	return errCannotPatch
+}
+
+// replaceLiteral rewrites the lines of a literal block scalar that changed.
+func (p *patcher) replaceLiteral(node *yamlv3.Node, value string) error {
+	oldBody := strings.TrimRight(node.Value, "\n")
+	newBody := strings.TrimRight(value, "\n")
+
+	if len(node.Value)-len(oldBody) != len(value)-len(newBody) {
+		return errCannotPatch
+	}
+
+	oldLines := strings.Split(oldBody, "\n")
+	newLines := strings.Split(newBody, "\n")
+
+	if len(oldLines) != len(newLines) {
+		return errCannotPatch
+	}
+
+	header := p.offset(node.Line, node.Column)
+	line := p.lineOf(header) + 1
+	indent := -1
+
+	for i := range oldLines {
+		if oldLines[i] == "" {
+			if newLines[i] != "" {
+				return errCannotPatch
+			}
+
+			line++
+			continue
+		}
+
+		if line >= len(p.lineStarts) {
+			return errCannotPatch
+		}
+
+		start, end := p.lineStarts[line], p.lineEnd(line)
+		source := p.line(line)
+		trimmed := strings.TrimLeft(source, " ")
+
+		if indent == -1 {
+			indent = len(source) - len(trimmed)
+		}
+
+		if !strings.HasPrefix(source, strings.Repeat(" ", indent)) || source[indent:] != oldLines[i] {
+			return errCannotPatch
+		}
+
+		if oldLines[i] != newLines[i] {
+			// the first line sets the indentation unless the header has an indicator
+			if newLines[i] == "" || strings.HasPrefix(newLines[i], " ") && strings.TrimLeft(oldBody, "\n") == strings.Join(oldLines[i:], "\n") {
+				return errCannotPatch
+			}
+
+			p.edits = append(p.edits, edit{start: start + indent, end: end, text: []byte(newLines[i])})
+		}
+
+		line++
+	}
+
+	return nil
+}
+
+// foldedLine is a line of a folded block scalar and the bounds of its text in
+// the value of the scalar.
+type foldedLine struct {
+	line       int
+	start, end int
+}
+
+// replaceFolded rewrites the lines of a folded block scalar that changed. Only
+// the words may change, the new value must keep the white space of the old one
+// so its lines fold the same way, like when an image is replaced by another.
+func (p *patcher) replaceFolded(node *yamlv3.Node, value string) error {
+	oldBody := strings.TrimRight(node.Value, "\n")
+	newBody := strings.TrimRight(value, "\n")
+
+	if len(node.Value)-len(oldBody) != len(value)-len(newBody) {
+		return errCannotPatch
+	}
+
+	header := p.offset(node.Line, node.Column)
+	last := p.lastLine(node)
+	folded := strings.Builder{}
+	lines := []foldedLine{}
+	indent, breaks := -1, 0
+
+	for line := p.lineOf(header) + 1; line <= last; line++ {
+		source := p.line(line)
+
+		if strings.TrimSpace(source) == "" {
+			breaks++
+			continue
+		}
+
+		if indent == -1 {
+			indent = indentOf(source)
+		}
+
+		// more indented lines keep their line breaks
+		if indentOf(source) != indent || strings.HasPrefix(source[indent:], "\t") {
+			return errCannotPatch
+		}
+
+		if len(lines) != 0 && breaks == 0 {
+			folded.WriteByte(' ')
+		}
+
+		folded.WriteString(strings.Repeat("\n", breaks))
+		lines = append(lines, foldedLine{line: line, start: folded.Len(), end: folded.Len() + len(source) - indent})
+		folded.WriteString(source[indent:])
+		breaks = 0
+	}
+
+	// the value must be what the lines fold to, or the lines aren't understood
+	if folded.String() != oldBody {
+		return errCannotPatch
+	}
+
+	offset, ok := alignWords(oldBody, newBody)
+	if !ok {
+		return errCannotPatch
+	}
+
+	for _, l := range lines {
+		start, end := offset(l.start), offset(l.end)
+		if start < 0 || end < 0 {
+			return errCannotPatch
+		}
+
+		if text := newBody[start:end]; text != oldBody[l.start:l.end] {
+			p.edits = append(p.edits, edit{start: p.lineStarts[l.line] + indent, end: p.lineEnd(l.line), text: []byte(text)})
+		}
+	}
+
+	return nil
+}
+
+var wordsRegexp = regexp.MustCompile(`\s+|\S+`)
+
+// alignWords returns a function mapping the offsets of old to the offsets of
+// new, when both have the same white space between their words. The offsets
+// within a word map to -1.
+func alignWords(old, new string) (func(int) int, bool) {
+	oldTokens := wordsRegexp.FindAllStringIndex(old, -1)
+	newTokens := wordsRegexp.FindAllStringIndex(new, -1)
+
+	if len(oldTokens) != len(newTokens) {
+		return nil, false
+	}
+
+	for i := range oldTokens {
+		oldToken := old[oldTokens[i][0]:oldTokens[i][1]]
+		newToken := new[newTokens[i][0]:newTokens[i][1]]
+
+		if strings.TrimSpace(oldToken) == "" || strings.TrimSpace(newToken) == "" {
+			if oldToken != newToken {
+				return nil, false
+			}
+		}
+	}
+
+	return func(offset int) int {
+		if offset == len(old) {
+			return len(new)
+		}
+
+		for i, token := range oldTokens {
+			if offset < token[0] || offset >= token[1] {
+				continue
+			}
+
+			if strings.TrimSpace(old[token[0]:token[1]]) == "" {
+				return newTokens[i][0] + offset - token[0]
+			}
+
+			if offset == token[0] {
+				return newTokens[i][0]
+			}
+		}
+
+		return -1
+	}, true
+}
+
+// replaceValue re-renders the whole value of a mapping key.
+func (p *patcher) replaceValue(key, node *yamlv3.Node, value interface{}) error {
+	colon, err := p.colonAfter(key)
+	if err != nil {
+		return err
+	}
+
+	text := &bytes.Buffer{}
+	if err := p.renderValue(text, value, key.Column-1); err != nil {
+		return err
+	}
+
+	end := p.blockEnd(node)
+	p.edits = append(p.edits, edit{start: colon, end: end, text: text.Bytes()})
+	return nil
+}
+
+// deleteKey removes a key and its value from a block mapping.
+func (p *patcher) deleteKey(key, value *yamlv3.Node) error {
+	start := p.lineStarts[key.Line-1]
+
+	if strings.TrimSpace(string(p.src[start:p.offset(key.Line, key.Column)])) != "" {
+		return errCannotPatch
+	}
+
+	p.edits = append(p.edits, edit{start: start, end: p.blockEnd(value)})
+	return nil
+}
+
+// apply returns the source with all the recorded edits applied.
+func (p *patcher) apply() ([]byte, error) {
+	sort.SliceStable(p.edits, func(i, j int) bool {
+		return p.edits[i].start < p.edits[j].start
+	})
+
+	result := &bytes.Buffer{}
+	last := 0
+
+	for _, e := range p.edits {
+		if e.start < last {
+			return nil, errCannotPatch
+		}
+
+		result.Write(p.src[last:e.start])
+		result.Write(p.lineBreaks(e.text))
+		last = e.end
+	}
+
+	result.Write(p.src[last:])
+	return result.Bytes(), nil
+}
+
+// lineBreaks returns the text with the line breaks of the source.
+func (p *patcher) lineBreaks(text []byte) []byte {
+	if p.newline == "\n" {
+		return text
+	}
+
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(text, []byte("\n"), []byte(p.newline))
+}
+
+// renderValue writes the value of a mapping key starting right at the colon.
+func (p *patcher) renderValue(w *bytes.Buffer, value interface{}, keyIndent int) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			w.WriteString(": {}\n")
+			return nil
+		}
+
+		w.WriteString(":\n")
+		return p.renderMapping(w, v, keyIndent+2)
+	case []interface{}:
+		if len(v) == 0 {
+			w.WriteString(": []\n")
+			return nil
+		}
+
+		w.WriteString(":\n")
+		seqIndent := keyIndent + 2
+		if p.indentless {
+			seqIndent = keyIndent
+		}
+
+		return p.renderSequence(w, v, seqIndent)
+	case string:
+		if strings.Contains(v, "\n") {
+			w.WriteString(":")
+			renderLiteral(w, v, keyIndent+2)
+			return nil
+		}
+	}
+
+	text, err := renderScalar(value)
+	if err != nil {
+		return err
+	}
+
+	w.WriteString(": " + text + "\n")
+	return nil
+}
+
+func (p *patcher) renderMapping(w *bytes.Buffer, m map[string]interface{}, indent int) error {
+	for _, k := range orderedKeys(m) {
+		keyText, err := renderScalar(k)
+		if err != nil {
+			return err
+		}
+
+		w.WriteString(strings.Repeat(" ", indent) + keyText)
+
+		if err := p.renderValue(w, m[k], indent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *patcher) renderSequence(w *bytes.Buffer, s []interface{}, indent int) error {
+	for _, item := range s {
+		itemBuff := &bytes.Buffer{}
+
+		switch v := item.(type) {
+		case map[string]interface{}:
+			if len(v) == 0 {
+				itemBuff.WriteString(strings.Repeat(" ", indent+2) + "{}\n")
+				break
+			}
+
+			if err := p.renderMapping(itemBuff, v, indent+2); err != nil {
+				return err
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				itemBuff.WriteString(strings.Repeat(" ", indent+2) + "[]\n")
+				break
+			}
+
+			if err := p.renderSequence(itemBuff, v, indent+2); err != nil {
+				return err
+			}
+		default:
+			text, err := renderScalar(v)
+			if err != nil {
+				return err
+			}
+
+			itemBuff.WriteString(strings.Repeat(" ", indent+2) + text + "\n")
+		}
+
+		w.WriteString(strings.Repeat(" ", indent) + "- ")
+		w.Write(itemBuff.Bytes()[indent+2:])
+	}
+
+	return nil
+}
+
+// renderScalar renders a single line scalar the way yaml.v3 would.
+func renderScalar(value interface{}) (string, error) {
+	if value == nil {
+		return "null", nil
+	}
+
+	b, err := yamlv3.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSuffix(string(b), "\n")
+	if strings.Contains(text, "\n") {
+		return "", errCannotPatch
+	}
+
+	return text, nil
+}
+
+// renderLiteral writes value as a literal block scalar whose lines are indented
+// two spaces past the key. An indentation indicator is written when the first
+// line starts with a space, it would be taken for indentation otherwise.
+func renderLiteral(w *bytes.Buffer, value string, indent int) {
+	body := strings.TrimRight(value, "\n")
+	header := " |"
+
+	if strings.HasPrefix(strings.TrimLeft(value, "\n"), " ") {
+		header += "2"
+	}
+
+	switch len(value) - len(body) {
+	case 0:
+		header += "-"
+	case 1:
+	default:
+		header += "+"
+	}
+
+	w.WriteString(header + "\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(value, "\n"), "\n") {
+		if line != "" {
+			w.WriteString(strings.Repeat(" ", indent) + line)
+		}
+		w.WriteByte('\n')
+	}
+}
+
+// orderedKeys returns the keys of m sorted alphabetically, with "name" first
+// as is customary for kubernetes objects.
+func orderedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == "name" || keys[j] == "name" {
+			return keys[i] == "name"
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// blockEnd returns the offset of the line following the last line holding
+// content of node.
+func (p *patcher) blockEnd(node *yamlv3.Node) int {
+	line := p.lastLine(node)
+
+	if line+1 < len(p.lineStarts) {
+		return p.lineStarts[line+1]
+	}
+
+	return len(p.src)
+}
+
+// lastLine returns the zero based number of the last line holding content of
+// node. The last scalar of a node may span several lines, so every following
+// line indented deeper than the line the scalar starts on is included.
+func (p *patcher) lastLine(node *yamlv3.Node) int {
+	for len(node.Content) != 0 {
+		node = node.Content[len(node.Content)-1]
+	}
+
+	first := node.Line - 1
+	base := indentOf(p.line(first))
+	line := first
+
+	for line+1 < len(p.lineStarts) {
+		next := p.line(line + 1)
+
+		if strings.TrimSpace(next) != "" && indentOf(next) <= base {
+			break
+		}
+
+		line++
+	}
+
+	// trailing blank lines and comments belong to whatever follows
+	for line > first {
+		trimmed := strings.TrimSpace(p.line(line))
+
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+
+		line--
+	}
+
+	return line
+}
+
+// line returns the text of a zero based line.
+func (p *patcher) line(line int) string {
+	return string(p.src[p.lineStarts[line]:p.lineEnd(line)])
+}
+
+// quotedEnd finds the end of a quoted scalar starting at start.
+func (p *patcher) quotedEnd(start int, quote byte) (int, error) {
+	if start >= len(p.src) || p.src[start] != quote {
+		return 0, errCannotPatch
+	}
+
+	for i := start + 1; i < len(p.src); i++ {
+		switch {
+		case quote == '"' && p.src[i] == '\\':
+			i++
+		case p.src[i] == quote && quote == '\'' && i+1 < len(p.src) && p.src[i+1] == '\'':
+			i++
+		case p.src[i] == quote:
+			return i + 1, nil
+		}
+	}
+
+	return 0, errCannotPatch
+}
+
+// colonAfter returns the offset of the ':' separating a key from its value.
+func (p *patcher) colonAfter(key *yamlv3.Node) (int, error) {
+	start := p.offset(key.Line, key.Column)
+
+	if key.Style != 0 {
+		end, err := p.quotedEnd(start, p.src[start])
+		if err != nil {
+			return 0, err
+		}
+		start = end
+	} else {
+		start += len(key.Value)
+	}
+
+	for i := start; i < len(p.src) && p.src[i] != '\n'; i++ {
+		if p.src[i] == ':' {
+			return i, nil
+		}
+
+		if p.src[i] != ' ' {
+			break
+		}
+	}
+
+	return 0, errCannotPatch
+}
+
+// offset converts a one based line and column into a byte offset.
+func (p *patcher) offset(line, column int) int {
+	offset := p.lineStarts[line-1]
+
+	for i := 1; i < column && offset < len(p.src); i++ {
+		_, size := utf8.DecodeRune(p.src[offset:])
+		offset += size
+	}
+
+	return offset
+}
+
+// lineOf returns the zero based line number holding offset.
+func (p *patcher) lineOf(offset int) int {
+	return sort.Search(len(p.lineStarts), func(i int) bool {
+		return p.lineStarts[i] > offset
+	}) - 1
+}
+
+// lineEnd returns the offset of the end of a zero based line, excluding the line break.
+func (p *patcher) lineEnd(line int) int {
+	end := len(p.src)
+	if line+1 < len(p.lineStarts) {
+		end = p.lineStarts[line+1] - 1
+	} else if end > p.lineStarts[line] && p.src[end-1] == '\n' {
+		end--
+	}
+
+	if end > p.lineStarts[line] && p.src[end-1] == '\r' {
+		end--
+	}
+
+	return end
+}
+
+func lineStarts(src []byte) []int {
+	starts := []int{0}
+
+	for i, b := range src {
+		if b == '\n' && i+1 < len(src) {
+			starts = append(starts, i+1)
+		}
+	}
+
+	return starts
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// isIndentless reports whether the first block sequence found under a mapping
+// key uses the same indentation as the key, which is what kubernetes tooling
+// usually emits. Documents without block sequences are indentless.
+func isIndentless(node *yamlv3.Node) bool {
+	if indentless, ok := sequenceIndentation(node); ok {
+		return indentless
+	}
+
+	return true
+}
+
+// sequenceIndentation looks for the first block sequence under a mapping key,
+// depth first, and reports whether it is indentless. ok is false if there is
+// none.
+func sequenceIndentation(node *yamlv3.Node) (indentless, ok bool) {
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if value.Kind == yamlv3.SequenceNode && value.Style&yamlv3.FlowStyle == 0 &&
+				len(value.Content) != 0 && value.Line != key.Line {
+				return value.Column == key.Column, true
+			}
+
+			if indentless, ok := sequenceIndentation(value); ok {
+				return indentless, true
+			}
+		}
+
+		return false, false
+	}
+
+	for _, child := range node.Content {
+		if indentless, ok := sequenceIndentation(child); ok {
+			return indentless, true
+		}
+	}
+
+	return false, false
+}
@@ -0,0 +1,282 @@
+package pullspec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("patchYaml", func() {
+	decode := func(src string) map[string]interface{} {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+		return data.Object
+	}
+
+	patch := func(src string, change func(obj map[string]interface{})) string {
+		original := decode(src)
+		modified := decode(src)
+		change(modified)
+
+		result, err := patchYaml([]byte(src), original, modified)
+		Expect(err).To(Succeed())
+		Expect(decode(string(result))).To(Equal(modified))
+		return string(result)
+	}
+
+	const src = `# A meaningful comment
+kind: ClusterServiceVersion
+metadata:
+  annotations:
+    containerImage: "registry.io/foo:1"   # quoted
+    other: 'registry.io/bar:1'
+spec:
+  description: |
+    Some long description using registry.io/foo:1
+    that is wrapped over multiple lines.
+  install:
+    spec:
+      deployments:
+      - spec:
+          template:
+            spec:
+              containers:
+              - name: c1
+                image: registry.io/foo:1 # plain
+`
+
+	It("should keep the file untouched without changes", func() {
+		result := patch(src, func(map[string]interface{}) {})
+		Expect(result).To(Equal(src))
+	})
+
+	It("should only rewrite the changed scalars", func() {
+		result := patch(src, func(obj map[string]interface{}) {
+			annotations := obj["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+			annotations["containerImage"] = "registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+			annotations["other"] = "registry.io/bar@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+			spec := obj["spec"].(map[string]interface{})
+			spec["description"] = "Some long description using registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111\nthat is wrapped over multiple lines.\n"
+
+			deployments := spec["install"].(map[string]interface{})["spec"].(map[string]interface{})["deployments"].([]interface{})
+			container := deployments[0].(map[string]interface{})["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0]
+			container.(map[string]interface{})["image"] = "registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		})
+
+		Expect(result).To(Equal(`# A meaningful comment
+kind: ClusterServiceVersion
+metadata:
+  annotations:
+    containerImage: "registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111"   # quoted
+    other: 'registry.io/bar@sha256:1111111111111111111111111111111111111111111111111111111111111111'
+spec:
+  description: |
+    Some long description using registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111
+    that is wrapped over multiple lines.
+  install:
+    spec:
+      deployments:
+      - spec:
+          template:
+            spec:
+              containers:
+              - name: c1
+                image: registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111 # plain
+`))
+	})
+
+	It("should add new keys at the end of a mapping", func() {
+		result := patch(src, func(obj map[string]interface{}) {
+			obj["spec"].(map[string]interface{})["relatedImages"] = []interface{}{
+				map[string]interface{}{"name": "c1", "image": "registry.io/foo:1"},
+			}
+		})
+
+		Expect(result).To(Equal(src + `  relatedImages:
+  - name: c1
+    image: registry.io/foo:1
+`))
+	})
+
+	It("should indent new sequences like the existing ones", func() {
+		const indented = `kind: ClusterServiceVersion
+metadata:
+  name: operator
+spec:
+  install:
+    spec:
+      deployments:
+        - name: operator
+`
+		result := patch(indented, func(obj map[string]interface{}) {
+			obj["spec"].(map[string]interface{})["relatedImages"] = []interface{}{
+				map[string]interface{}{"name": "c1", "image": "registry.io/foo:1"},
+			}
+		})
+
+		Expect(result).To(Equal(indented + `  relatedImages:
+    - name: c1
+      image: registry.io/foo:1
+`))
+	})
+
+	It("should keep the line breaks of the file", func() {
+		crlf := strings.ReplaceAll(src, "\n", "\r\n")
+		result := patch(crlf, func(obj map[string]interface{}) {
+			obj["spec"].(map[string]interface{})["relatedImages"] = []interface{}{
+				map[string]interface{}{"name": "c1", "image": "registry.io/foo:1"},
+			}
+		})
+
+		Expect(result).To(Equal(crlf + "  relatedImages:\r\n  - name: c1\r\n    image: registry.io/foo:1\r\n"))
+	})
+
+	It("should replace and remove values", func() {
+		const withRelatedImages = `kind: ClusterServiceVersion
+spec:
+  relatedImages:
+  - name: a
+    image: registry.io/a:1
+  # the version
+  version: 1.0.0
+`
+		result := patch(withRelatedImages, func(obj map[string]interface{}) {
+			obj["spec"].(map[string]interface{})["relatedImages"] = []interface{}{
+				map[string]interface{}{"name": "a", "image": "registry.io/a:1"},
+				map[string]interface{}{"name": "b", "image": "registry.io/b:1"},
+			}
+		})
+
+		Expect(result).To(Equal(`kind: ClusterServiceVersion
+spec:
+  relatedImages:
+  - name: a
+    image: registry.io/a:1
+  - name: b
+    image: registry.io/b:1
+  # the version
+  version: 1.0.0
+`))
+
+		result = patch(withRelatedImages, func(obj map[string]interface{}) {
+			delete(obj["spec"].(map[string]interface{}), "relatedImages")
+		})
+
+		Expect(result).To(Equal(`kind: ClusterServiceVersion
+spec:
+  # the version
+  version: 1.0.0
+`))
+	})
+
+	It("should write an indentation indicator for literals starting with a space", func() {
+		const withDescription = `kind: ClusterServiceVersion
+spec:
+  description: short
+`
+		for _, description := range []string{"  indented\nregistry.io/foo:1", "\n indented\nregistry.io/foo:1\n"} {
+			result := patch(withDescription, func(obj map[string]interface{}) {
+				obj["spec"].(map[string]interface{})["description"] = description
+			})
+
+			Expect(result).To(ContainSubstring("description: |2"))
+		}
+
+		result := patch(src, func(obj map[string]interface{}) {
+			obj["spec"].(map[string]interface{})["description"] = "  Some long description using registry.io/foo:1\nthat is wrapped over multiple lines.\n"
+		})
+
+		Expect(result).To(ContainSubstring("description: |2"))
+	})
+
+	It("should only rewrite the changed lines of folded scalars", func() {
+		const folded = `kind: ClusterServiceVersion
+spec:
+  description: >
+    Some long description using registry.io/foo:1
+    that is wrapped over multiple lines.
+
+    Another paragraph about registry.io/foo:1.
+  version: 1.0.0
+`
+		const pinned = "registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+		replace := func(old, new string, n int) func(obj map[string]interface{}) {
+			return func(obj map[string]interface{}) {
+				spec := obj["spec"].(map[string]interface{})
+				spec["description"] = strings.Replace(spec["description"].(string), old, new, n)
+			}
+		}
+
+		result := patch(folded, replace("registry.io/foo:1", pinned, -1))
+		Expect(result).To(Equal(strings.ReplaceAll(folded, "registry.io/foo:1", pinned)))
+
+		// a value folding differently is rendered again
+		result = patch(folded, replace(" that", "\nthat", 1))
+		Expect(result).ToNot(ContainSubstring("description: >"))
+	})
+
+	It("should rewrite scalars in flow collections", func() {
+		const json = `{
+  "kind": "ClusterServiceVersion",
+  "spec": {"image": "registry.io/a:1", "other": 1}
+}
+`
+		result := patch(json, func(obj map[string]interface{}) {
+			obj["spec"].(map[string]interface{})["image"] = "registry.io/a@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		})
+
+		Expect(result).To(Equal(`{
+  "kind": "ClusterServiceVersion",
+  "spec": {"image": "registry.io/a@sha256:1111111111111111111111111111111111111111111111111111111111111111", "other": 1}
+}
+`))
+	})
+
+	It("should refuse structural changes in flow collections", func() {
+		const json = `{"kind": "ClusterServiceVersion", "spec": {}}`
+		original := decode(json)
+		modified := decode(json)
+		modified["spec"].(map[string]interface{})["relatedImages"] = []interface{}{}
+
+		_, err := patchYaml([]byte(json), original, modified)
+		Expect(err).To(MatchError(errCannotPatch))
+	})
+
+	It("should dump an OperatorCSV read from a file", func() {
+		dir, err := os.MkdirTemp("", "patch")
+		Expect(err).To(Succeed())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "csv.yaml")
+		Expect(os.WriteFile(path, []byte(src), 0600)).To(Succeed())
+
+		csv, err := NewOperatorCSVFromFile(path, nil)
+		Expect(err).To(Succeed())
+
+		buff := &bytes.Buffer{}
+		Expect(csv.Dump(buff)).To(Succeed())
+		Expect(buff.String()).To(Equal(src))
+
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("registry.io/foo:1"): *imagename.Parse("registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+		})).To(Succeed())
+		Expect(csv.Dump(nil)).To(Succeed())
+
+		b, err := os.ReadFile(path)
+		Expect(err).To(Succeed())
+		Expect(string(b)).To(ContainSubstring(`containerImage: "registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111"   # quoted`))
+		Expect(string(b)).To(ContainSubstring(`image: registry.io/foo@sha256:1111111111111111111111111111111111111111111111111111111111111111 # plain`))
+		Expect(string(b)).To(ContainSubstring("Some long description using registry.io/foo:1\n"))
+	})
+})
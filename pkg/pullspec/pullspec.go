@@ -1,22 +1,21 @@
 package pullspec
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
 	"io/fs"
 
 	"github.com/operator-framework/operator-manifest-tools/internal/utils"
 	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
-	yamlv3 "gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 )
 
 // NamedPullSpec is an interface that allows for some elements
@@ -24,15 +23,89 @@ import (
 type NamedPullSpec interface {
 	fmt.Stringer
 	Name() string
+	Kind() PullSpecKind
 	Image() string
 	Data() map[string]interface{}
 	SetImage(string)
 	AsYamlObject() map[string]interface{}
+	Location() Location
+	Owner() Owner
 }
 
+// Owner is the workload a pull spec belongs to.
+type Owner struct {
+	// Deployment is the name of the CSV deployment, or of the workload manifest,
+	// holding the pull spec.
+	Deployment string `json:"deployment,omitempty"`
+	// Container is the name of the container holding the pull spec.
+	Container string `json:"container,omitempty"`
+}
+
+// PullSpecKind is the kind of field a pull spec was found in.
+type PullSpecKind string
+
+const (
+	// KindContainer is the image of a container.
+	KindContainer PullSpecKind = "container"
+	// KindInitContainer is the image of an init container.
+	KindInitContainer PullSpecKind = "initContainer"
+	// KindRelatedImage is an image of the CSV relatedImages.
+	KindRelatedImage PullSpecKind = "relatedImage"
+	// KindRelatedImageEnv is the value of a RELATED_IMAGE_ env var.
+	KindRelatedImageEnv PullSpecKind = "relatedImageEnv"
+	// KindAnnotation is an image found by the heuristic in an annotation or any
+	// other string.
+	KindAnnotation PullSpecKind = "annotation"
+	// KindImageVolume is the reference of an image volume.
+	KindImageVolume PullSpecKind = "imageVolume"
+)
+
 type namedPullSpec struct {
 	imageKey string
 	data     map[string]interface{}
+	location Location
+	owner    Owner
+	// doc is the CSV whose index holds the pull spec, see OperatorCSV.attach.
+	doc *document
+}
+
+func (named *namedPullSpec) attach(doc *document) {
+	named.doc = doc
+}
+
+// imageChanged drops the index of the CSV holding the pull spec.
+func (named *namedPullSpec) imageChanged() {
+	if named.doc != nil {
+		named.doc.invalidate()
+	}
+}
+
+// Owner returns the workload the pull spec belongs to. It is empty for
+// pull specs found outside of a workload.
+func (named *namedPullSpec) Owner() Owner {
+	return named.owner
+}
+
+func (named *namedPullSpec) setOwner(owner Owner) {
+	named.owner = owner
+}
+
+func (named *namedPullSpec) ownerData() map[string]interface{} {
+	return named.data
+}
+
+// Location returns where the pull spec was found.
+func (named *namedPullSpec) Location() Location {
+	return named.location
+}
+
+func (named *namedPullSpec) setLocation(location Location) {
+	named.location = location
+}
+
+// imagePath returns the path of the image relative to the pull spec data.
+func (named *namedPullSpec) imagePath() []interface{} {
+	return []interface{}{named.imageKey}
 }
 
 // Name returns the name of the pull spec data.
@@ -53,6 +126,7 @@ func (named *namedPullSpec) Data() map[string]interface{} {
 // SetImage will override the image of the pull spec
 func (named *namedPullSpec) SetImage(image string) {
 	named.data[named.imageKey] = image
+	named.imageChanged()
 }
 
 // AsYamlObject returns the pull spec as an object
@@ -73,6 +147,11 @@ func (container *Container) String() string {
 	return fmt.Sprintf("container %s", container.Name())
 }
 
+// Kind returns the kind of the pullspec.
+func (container *Container) Kind() PullSpecKind {
+	return KindContainer
+}
+
 // NewContainer returns a container pullspec
 func NewContainer(data interface{}) (*Container, error) {
 	dataMap, ok := data.(map[string]interface{})
@@ -104,6 +183,11 @@ func (container *InitContainer) String() string {
 	return fmt.Sprintf("initcontainer %s", container.Name())
 }
 
+// Kind returns the kind of the pullspec.
+func (container *InitContainer) Kind() PullSpecKind {
+	return KindInitContainer
+}
+
 // NewInitContainer returns a new init container pullspec.
 func NewInitContainer(data interface{}) (*InitContainer, error) {
 	dataMap, ok := data.(map[string]interface{})
@@ -124,6 +208,75 @@ func NewInitContainer(data interface{}) (*InitContainer, error) {
 	}, nil
 }
 
+// ImageVolume is a pull spec representing the volumes of a pod mounting an
+// image, its reference is the image. The other fields of the image volume,
+// like its pullPolicy, are left untouched.
+type ImageVolume struct {
+	namedPullSpec
+	volume map[string]interface{}
+}
+
+// String returns a string representation of the pullspec.
+func (imageVolume *ImageVolume) String() string {
+	return fmt.Sprintf("volume %s", imageVolume.Name())
+}
+
+// Kind returns the kind of the pullspec.
+func (imageVolume *ImageVolume) Kind() PullSpecKind {
+	return KindImageVolume
+}
+
+// Name returns the name of the volume.
+func (imageVolume *ImageVolume) Name() string {
+	return strings.TrimSpace(fmt.Sprintf("%v", imageVolume.volume["name"]))
+}
+
+// AsYamlObject returns the pullspec as a map[string]interface{}.
+func (imageVolume *ImageVolume) AsYamlObject() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  imageVolume.Name(),
+		"image": imageVolume.Image(),
+	}
+}
+
+func (imageVolume *ImageVolume) ownerData() map[string]interface{} {
+	return imageVolume.volume
+}
+
+// isImageVolume returns true if data is a volume mounting an image.
+func isImageVolume(data interface{}) bool {
+	volume, _ := data.(map[string]interface{})
+	_, ok := volume["image"]
+	return ok
+}
+
+// NewImageVolume returns a new image volume pullspec.
+func NewImageVolume(data interface{}) (*ImageVolume, error) {
+	volume, ok := data.(map[string]interface{})
+
+	if !ok {
+		return nil, errors.New("expected map[string]interface{} type")
+	}
+
+	image, ok := volume["image"].(map[string]interface{})
+
+	if !ok {
+		return nil, errors.New("expected an image volume")
+	}
+
+	if _, ok := image["reference"].(string); !ok {
+		return nil, utils.NewError(nil, "%v: the image volume reference is required", volume["name"])
+	}
+
+	return &ImageVolume{
+		namedPullSpec: namedPullSpec{
+			imageKey: "reference",
+			data:     image,
+		},
+		volume: volume,
+	}, nil
+}
+
 // RelatedImage is a pullspec representing the CSV relatedImage field.
 type RelatedImage struct {
 	namedPullSpec
@@ -134,6 +287,26 @@ func (relatedImage *RelatedImage) String() string {
 	return fmt.Sprintf("relatedImage %s", relatedImage.Name())
 }
 
+// Kind returns the kind of the pullspec.
+func (relatedImage *RelatedImage) Kind() PullSpecKind {
+	return KindRelatedImage
+}
+
+// AsYamlObject returns the pullspec as an object, keeping the extra fields
+// of the relatedImages entry.
+func (relatedImage *RelatedImage) AsYamlObject() map[string]interface{} {
+	obj := make(map[string]interface{}, len(relatedImage.data))
+
+	for key, value := range relatedImage.data {
+		obj[key] = value
+	}
+
+	obj["name"] = relatedImage.Name()
+	obj["image"] = relatedImage.Image()
+
+	return obj
+}
+
 // NewRelatedImage returns a new related image pullspec.
 func NewRelatedImage(data interface{}) (*RelatedImage, error) {
 	dataMap, ok := data.(map[string]interface{})
@@ -154,6 +327,7 @@ func NewRelatedImage(data interface{}) (*RelatedImage, error) {
 // that start with RELATED_IMAGE_.
 type RelatedImageEnv struct {
 	namedPullSpec
+	env map[string]interface{}
 }
 
 // String returns a string representation of the pullspec.
@@ -161,9 +335,18 @@ func (relatedImageEnv *RelatedImageEnv) String() string {
 	return fmt.Sprintf("%s var", relatedImageEnv.Name())
 }
 
+// Kind returns the kind of the pullspec.
+func (relatedImageEnv *RelatedImageEnv) Kind() PullSpecKind {
+	return KindRelatedImageEnv
+}
+
+func (relatedImageEnv *RelatedImageEnv) ownerData() map[string]interface{} {
+	return relatedImageEnv.env
+}
+
 // Name returns the name of the related image.
 func (relatedImageEnv *RelatedImageEnv) Name() string {
-	text := fmt.Sprintf("%v", relatedImageEnv.data["name"])
+	text := fmt.Sprintf("%v", relatedImageEnv.env["name"])
 	return strings.TrimSpace(strings.ToLower(text[len("RELATED_IMAGE_"):]))
 }
 
@@ -182,6 +365,19 @@ func NewRelatedImageEnv(data map[string]interface{}) *RelatedImageEnv {
 			imageKey: "value",
 			data:     data,
 		},
+		env: data,
+	}
+}
+
+// NewRelatedImageEnvFromConfigMap returns a new related image env pullspec for an
+// env var whose value comes from the key of a ConfigMap data.
+func NewRelatedImageEnvFromConfigMap(env, configMapData map[string]interface{}, key string) *RelatedImageEnv {
+	return &RelatedImageEnv{
+		namedPullSpec: namedPullSpec{
+			imageKey: key,
+			data:     configMapData,
+		},
+		env: env,
 	}
 }
 
@@ -216,11 +412,17 @@ func (annotation *Annotation) String() string {
 	return fmt.Sprintf("annotation %s", annotation.Name())
 }
 
+// Kind returns the kind of the pullspec.
+func (annotation *Annotation) Kind() PullSpecKind {
+	return KindAnnotation
+}
+
 // SetImage will replace the image string with the provided image string.
 func (annotation *Annotation) SetImage(image string) {
 	i, j := annotation.startI, annotation.endI
 	text := fmt.Sprintf("%v", annotation.data[annotation.imageKey])
 	annotation.data[annotation.imageKey] = fmt.Sprintf("%v%s%v", text[:i], image, text[j:])
+	annotation.imageChanged()
 }
 
 // Name returns the name of the pullspec.
@@ -228,8 +430,8 @@ func (annotation *Annotation) Name() string {
 	image := imagename.Parse(annotation.Image())
 	tag := image.Tag
 
-	if strings.HasPrefix(tag, "sha256") {
-		tag = tag[len("sha256:"):]
+	if image.Digest == "" && image.HasDigest() {
+		tag = tag[strings.Index(tag, ":")+1:]
 	}
 	return fmt.Sprintf("%s-%s-annotation", image.Repo, tag)
 }
@@ -245,14 +447,25 @@ func (annotation *Annotation) AsYamlObject() map[string]interface{} {
 // OperatorCSV represents the CSV data and holds information
 // regarding how to parse the image strings.
 type OperatorCSV struct {
-	path              string
-	data              unstructured.Unstructured
-	pullspecHeuristic Heuristic
+	*document
+
+	// configMaps are the ConfigMaps of the bundle, RELATED_IMAGE_ env vars
+	// may reference them.
+	configMaps []*Manifest
+
+	// locators find the pull specs of the CSV.
+	locators []Locator
+	// annotationKeys are the annotations known to hold images.
+	annotationKeys stringSlice
 }
 
 // NewOperatorCSV creates a OperatorCSV using the data provided via an unstructured kubernetes object.
 func NewOperatorCSV(path string, data *unstructured.Unstructured, pullSpecHeuristic Heuristic) (*OperatorCSV, error) {
-	if data.GetKind() != operatorCsvKind {
+	return newOperatorCSV(&document{data: *data, path: path}, pullSpecHeuristic)
+}
+
+func newOperatorCSV(doc *document, pullSpecHeuristic Heuristic) (*OperatorCSV, error) {
+	if doc.data.GetKind() != operatorCsvKind {
 		return nil, utils.ErrNotClusterServiceVersion
 	}
 
@@ -260,35 +473,64 @@ func NewOperatorCSV(path string, data *unstructured.Unstructured, pullSpecHeuris
 		pullSpecHeuristic = DefaultHeuristic
 	}
 
+	doc.pullspecHeuristic = pullSpecHeuristic
+
 	return &OperatorCSV{
-		data:              *data,
-		path:              path,
-		pullspecHeuristic: pullSpecHeuristic,
+		document:       doc,
+		locators:       builtinLocators(),
+		annotationKeys: knownAnnotationKeys,
 	}, nil
 }
 
 const (
 	operatorCsvKind = "ClusterServiceVersion"
+	configMapKind   = "ConfigMap"
 )
 
 var ()
 
 // FromDirectory creates a NewOperatorCSV from the directory path provided.
 func FromDirectory(path string, pullSpecHeuristic Heuristic) ([]*OperatorCSV, error) {
+	operatorCSVs, manifests, err := findDocuments(path, pullSpecHeuristic)
+
+	if err != nil {
+		return nil, err
+	}
+
+	linkConfigMaps(operatorCSVs, manifests)
+
+	if len(operatorCSVs) > 1 {
+		log.Printf("found too many csvs in the directory")
+		return nil, utils.ErrTooManyCSVs
+	}
+
+	if len(operatorCSVs) == 0 {
+		log.Printf("failure to find operator manifests in the directory")
+		return nil, utils.ErrNoOperatorManifests
+	}
+
+	return operatorCSVs, nil
+}
+
+// findDocuments walks the directory path and creates an OperatorCSV for every
+// ClusterServiceVersion document found and a Manifest for every other kubernetes
+// object found.
+func findDocuments(path string, pullSpecHeuristic Heuristic) ([]*OperatorCSV, []*Manifest, error) {
 	operatorCSVs := []*OperatorCSV{}
+	manifests := []*Manifest{}
 
 	stat, err := os.Stat(path)
 
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, utils.NewErrIsNotDirectoryOrDoesNotExist(path)
+			return nil, nil, utils.NewErrIsNotDirectoryOrDoesNotExist(path)
 		}
 
-		return nil, err
+		return nil, nil, err
 	}
 
 	if !stat.IsDir() {
-		return nil, utils.NewErrIsNotDirectoryOrDoesNotExist(path)
+		return nil, nil, utils.NewErrIsNotDirectoryOrDoesNotExist(path)
 	}
 
 	err = filepath.Walk(path, func(path string, info fs.FileInfo, err error) error {
@@ -299,115 +541,122 @@ func FromDirectory(path string, pullSpecHeuristic Heuristic) ([]*OperatorCSV, er
 
 		log.Println(info.Name(), info.IsDir())
 
-		if info.IsDir() ||
-			!(strings.HasSuffix(info.Name(), ".yaml") || strings.HasSuffix(info.Name(), ".yml")) {
-			log.Printf("skipping non-yaml file without errors: %+v \n", info.Name())
+		if info.IsDir() || !isManifestFile(info.Name()) {
+			log.Printf("skipping non-manifest file without errors: %+v \n", info.Name())
 			return nil
 		}
 
 		log.Printf("visited file or dir: %q\n", path)
-		csv, err := NewOperatorCSVFromFile(path, pullSpecHeuristic)
-
-		if err != nil && errors.Is(err, utils.ErrNotClusterServiceVersion) {
-			log.Printf("skipping file because it's not a ClusterServiceVersion: %+v \n", info.Name())
-			return nil
-		}
+		file, err := readFile(path)
 
 		if err != nil {
 			log.Printf("failure reading the file: %+v \n", info.Name())
 			return err
 		}
 
-		operatorCSVs = append(operatorCSVs, csv)
+		for _, doc := range file.documents() {
+			if doc.data.GetKind() == operatorCsvKind {
+				csv, err := newOperatorCSV(doc, pullSpecHeuristic)
+
+				if err != nil {
+					return err
+				}
+
+				operatorCSVs = append(operatorCSVs, csv)
+				continue
+			}
+
+			manifests = append(manifests, newManifest(doc, pullSpecHeuristic))
+		}
+
 		return nil
 	})
 
 	if err != nil {
 		log.Printf("failure walking the directory: %+v \n", err)
-		return nil, err
-	}
-
-	if len(operatorCSVs) > 1 {
-		log.Printf("found too many csvs in the directory")
-		return nil, utils.ErrTooManyCSVs
+		return nil, nil, err
 	}
 
-	if len(operatorCSVs) == 0 {
-		log.Printf("failure to find operator manifests in the directory")
-		return nil, utils.ErrNoOperatorManifests
-	}
-
-	return operatorCSVs, nil
+	return operatorCSVs, manifests, nil
 }
 
-// NewOperatorCSVFromFile creates a NewOperatorCSV from a filepath.
+// NewOperatorCSVFromFile creates a NewOperatorCSV from a filepath. The file may
+// hold other documents, but only a single ClusterServiceVersion.
 func NewOperatorCSVFromFile(
 	path string,
 	pullSpecHeuristic Heuristic,
 ) (*OperatorCSV, error) {
-	data := &unstructured.Unstructured{}
-
-	fileData, err := os.ReadFile(path)
+	file, err := readFile(path)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// decode YAML into unstructured.Unstructured
-	dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
-	_, _, err = dec.Decode(fileData, nil, data)
+	var csv *OperatorCSV
+	manifests := []*Manifest{}
 
-	if err != nil {
-		return nil, err
-	}
+	for _, doc := range file.documents() {
+		if doc.data.GetKind() != operatorCsvKind {
+			manifests = append(manifests, newManifest(doc, pullSpecHeuristic))
+			continue
+		}
 
-	csv, err := NewOperatorCSV(path, data, pullSpecHeuristic)
+		if csv != nil {
+			return nil, utils.ErrTooManyCSVs
+		}
 
-	if err != nil {
-		return nil, err
+		csv, err = newOperatorCSV(doc, pullSpecHeuristic)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return csv, nil
-}
+	if csv == nil {
+		return nil, utils.ErrNotClusterServiceVersion
+	}
 
-// ToYaml will write the OperatorCSV to yaml string and return the bytes.
-func (csv *OperatorCSV) ToYaml() ([]byte, error) {
-	buff := bytes.Buffer{}
+	linkConfigMaps([]*OperatorCSV{csv}, manifests)
 
-	enc := yamlv3.NewEncoder(&buff)
-	enc.SetIndent(2)
+	return csv, nil
+}
 
-	err := enc.Encode(&csv.data.Object)
-	if err != nil {
-		return nil, err
+// linkConfigMaps gives each CSV the ConfigMaps found in the same directory.
+func linkConfigMaps(operatorCSVs []*OperatorCSV, manifests []*Manifest) {
+	for _, csv := range operatorCSVs {
+		for _, manifest := range manifests {
+			if manifest.data.GetKind() == configMapKind && filepath.Dir(manifest.path) == filepath.Dir(csv.path) {
+				csv.configMaps = append(csv.configMaps, manifest)
+			}
+		}
 	}
-
-	return buff.Bytes(), nil
 }
 
-// Dump will dump the csv yaml to a writer if provided or
-// the file the OperatorCSV started from if the filesystem is writable.
+// Dump will dump the csv yaml to a writer if provided or the file the
+// OperatorCSV started from if the filesystem is writable. In the latter case
+// the ConfigMaps holding the values of RELATED_IMAGE_ env vars are written too
+// when they changed.
 func (csv *OperatorCSV) Dump(writer io.Writer) error {
-	if writer == nil {
-		f, err := os.OpenFile(csv.path, os.O_TRUNC|os.O_WRONLY, 0755)
-		if err != nil {
-			return err
-		}
-		defer closeFile(f)
+	if err := csv.document.Dump(writer); err != nil {
+		return err
+	}
 
-		writer = f
+	if writer != nil {
+		return nil
 	}
 
-	b, err := csv.ToYaml()
+	dumped := map[*manifestFile]bool{csv.file: true}
 
-	if err != nil {
-		return err
-	}
+	for _, configMap := range csv.configMaps {
+		if !configMap.changed() || (configMap.file != nil && dumped[configMap.file]) {
+			continue
+		}
 
-	_, err = writer.Write(b)
+		dumped[configMap.file] = true
 
-	if err != nil {
-		return err
+		if err := configMap.Dump(nil); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -415,214 +664,306 @@ func (csv *OperatorCSV) Dump(writer io.Writer) error {
 
 // HasRelatedImages returns true with the CSV has RelatedImage pullspecs.
 func (csv *OperatorCSV) HasRelatedImages() bool {
-	pullSpecs, _ := csv.relatedImagePullSpecs()
-	return len(pullSpecs) != 0
+	return len(csv.index().relatedImages) != 0
 }
 
 // HasRelatedImageEnvs returns true with the CSV has RelatedImageEnv pullspecs.
 func (csv *OperatorCSV) HasRelatedImageEnvs() bool {
-	pullSpecs, _ := csv.relatedImageEnvPullSpecs()
-	return len(pullSpecs) > 0
+	return len(csv.index().relatedImageEnvs) > 0
+}
+
+// parseImage parses the image of the pull spec, see imagename.ParseStrict. A
+// malformed image returns an error, unless it was guessed in an annotation:
+// it's then logged and ignored, nil is returned.
+func (doc *document) parseImage(ps NamedPullSpec) (*imagename.ImageName, error) {
+	image, err := imagename.ParseStrict(ps.Image())
+
+	switch {
+	case err == nil:
+		return image, nil
+	case ps.Kind() == KindAnnotation:
+		log.Printf("%s - Ignoring pullspec for %s: %v", doc.path, ps.String(), err)
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%s - invalid pullspec for %s: %w", doc.path, ps.String(), err)
+	}
 }
 
 // GetPullSpecs will return a list of all the images found in via pullspecs.
+// Each image is listed once, in the order it is first found, as imagename.Parse
+// parses it. A malformed image returns an error, see imagename.ParseStrict.
 func (csv *OperatorCSV) GetPullSpecs() ([]*imagename.ImageName, error) {
 	pullspecs := make(map[imagename.ImageName]interface{})
 
-	namedList, err := csv.namedPullSpecs()
+	namedList, err := csv.NamedPullSpecs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	markers, err := csv.SkipMarkers()
 
 	if err != nil {
 		return nil, err
 	}
 
+	imageList := make([]*imagename.ImageName, 0, len(namedList))
+
 	for i := range namedList {
 		ps := namedList[i]
+
+		if markers.Skips(ps) {
+			log.Printf("Skipping pullspec for %s: %s", ps.String(), ps.Image())
+			continue
+		}
+
 		log.Printf("Found pullspec for %s: %s", ps.String(), ps.Image())
+		if parsed, err := csv.parseImage(ps); err != nil || parsed == nil {
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
 		image := imagename.Parse(ps.Image())
-		pullspecs[*image] = nil
-	}
 
-	imageList := make([]*imagename.ImageName, 0, len(pullspecs))
+		if _, ok := pullspecs[*image]; ok {
+			continue
+		}
 
-	for key := range pullspecs {
-		localKey := key
-		imageList = append(imageList, &localKey)
+		pullspecs[*image] = nil
+		imageList = append(imageList, image)
 	}
 
 	return imageList, nil
 }
 
 // ReplacePullSpecs will replace each pullspec found with the provide image.
+// Equivalent references, like nginx:1 and docker.io/library/nginx:1, match the
+// same replacement.
 func (csv *OperatorCSV) ReplacePullSpecs(replacement map[imagename.ImageName]imagename.ImageName) error {
-	pullspecs, err := csv.namedPullSpecs()
+	pullspecs, err := csv.NamedPullSpecs()
 	if err != nil {
 		return err
 	}
 
-	for _, pullspec := range pullspecs {
-		old := imagename.Parse(pullspec.Image())
-		new, ok := replacement[*old]
-
-		if ok && *old != new {
-			log.Printf("%s - Replaced pullspec for %s: %s -> %s", csv.path, pullspec.String(), *old, new)
-			pullspec.SetImage(new.String())
-		}
+	markers, err := csv.SkipMarkers()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return csv.replace(pullspecs, replacement, markers)
 }
 
-// ReplacePullSpecsEverywhere will replace image values in each pullspec throughout the entire OperatorCSV.
-func (csv *OperatorCSV) ReplacePullSpecsEverywhere(replacement map[imagename.ImageName]imagename.ImageName) error {
-	err := csv.ReplacePullSpecs(replacement)
+// replace replaces the images of the pull specs that aren't skipped, matching
+// equivalent references, see newReplacementIndex. A malformed image returns an
+// error.
+func (csv *OperatorCSV) replace(pullspecs []NamedPullSpec, replacement map[imagename.ImageName]imagename.ImageName, markers *SkipMarkers) error {
+	index, err := newReplacementIndex(replacement)
 
 	if err != nil {
 		return err
 	}
 
-	pullspecs := []NamedPullSpec{}
-	annotationPullSpecs, err := csv.annotationPullSpecs(knownAnnotationKeys)
+	for _, pullspec := range pullspecs {
+		parsed, err := csv.parseImage(pullspec)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	guessedAnnotationPullSpecs, err := csv.annotationPullSpecs(nil)
+		if parsed == nil {
+			continue
+		}
 
-	if err != nil {
-		return err
+		old, new, ok := index.lookup(parsed)
+
+		if !ok || old == new || pullspec.Image() == new.String() {
+			continue
+		}
+
+		if markers.Skips(pullspec) {
+			log.Printf("%s - Skipped pullspec for %s: %s", csv.path, pullspec.String(), old)
+			continue
+		}
+
+		log.Printf("%s - Replaced pullspec for %s: %s -> %s", csv.path, pullspec.String(), old, new)
+		pullspec.SetImage(new.String())
+		csv.invalidate()
 	}
 
-	pullspecs = append(pullspecs, annotationPullSpecs...)
-	pullspecs = append(pullspecs, guessedAnnotationPullSpecs...)
+	return nil
+}
 
-	err = csv.findPotentialPullSpecsNotInAnnotations(csv.data.Object, &pullspecs)
+// ReplacePullSpecsEverywhere will replace image values in each pullspec throughout the entire OperatorCSV.
+// The strings guessed to be images are only replaced within the scope of the
+// CSV, see SetScope.
+func (csv *OperatorCSV) ReplacePullSpecsEverywhere(replacement map[imagename.ImageName]imagename.ImageName) error {
+	pullspecs, err := csv.everywherePullSpecs()
 
 	if err != nil {
 		return err
 	}
 
-	for _, pullspec := range pullspecs {
-		old := imagename.Parse(pullspec.Image())
-		new, ok := replacement[*old]
+	markers, err := csv.SkipMarkers()
 
-		if ok && *old != new {
-			log.Printf("%s - Replaced pullspec for %s: %s -> %s", csv.path, pullspec.String(), *old, new)
-			pullspec.SetImage(new.String())
-		}
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return csv.replace(pullspecs, replacement, markers)
 }
 
 // SetRelatedImages will set the related images fields based on the CSV pullspecs discovered.
-func (csv *OperatorCSV) SetRelatedImages() error {
-	namedPullspecs, err := csv.namedPullSpecs()
+// The entries are sorted by name, the entries suffixed on conflicts following
+// the entry they conflicted with. By default existing entries are preserved, see
+// WithRelatedImagesPolicy.
+func (csv *OperatorCSV) SetRelatedImages(opts ...RelatedImagesOption) error {
+	return csv.setRelatedImages(nil, opts...)
+}
+
+// setRelatedImages sets the related images fields based on the CSV pullspecs
+// discovered and the extra pullspecs found elsewhere in the bundle.
+func (csv *OperatorCSV) setRelatedImages(extra []NamedPullSpec, opts ...RelatedImagesOption) error {
+	options := newRelatedImagesOptions(opts)
+	namedPullspecs, err := csv.NamedPullSpecs()
 
 	if err != nil {
 		return err
 	}
 
+	namedPullspecs = append(namedPullspecs, extra...)
+
 	if len(namedPullspecs) == 0 {
 		return nil
 	}
 
-	conflicts := []string{}
-	byName := map[string]NamedPullSpec{}
+	namedPullspecs = options.policy.filter(namedPullspecs)
+
+	names := []string{}
+	groups := map[string][]NamedPullSpec{}
+
 	for _, newPull := range namedPullspecs {
-		old, ok := byName[newPull.Name()]
+		name, err := options.name(newPull)
 
-		if !ok {
-			byName[newPull.Name()] = newPull
-			continue
+		if err != nil {
+			return err
 		}
 
-		if old.Image() == newPull.Image() {
-			continue
+		group, ok := groups[name]
+
+		if !ok {
+			names = append(names, name)
 		}
 
-		conflicts = append(conflicts, fmt.Sprintf("%s: %s X %s: %s",
-			old.String(), old.Image(), newPull.String(), newPull.Image()))
+		if !containsImage(group, newPull.Image()) {
+			groups[name] = append(group, newPull)
+		}
 	}
 
+	entries, conflicts := options.conflicts.resolve(names, groups)
+
 	if len(conflicts) > 0 {
 		return fmt.Errorf("%s - Found conflicts when setting relatedImages:\n%s", csv.path, strings.Join(conflicts, "\n"))
 	}
 
-	relatedImages := []map[string]interface{}{}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].less(entries[j])
+	})
 
-	for _, p := range byName {
-		log.Printf("%s - Set relateImage %s (from %s): %s\n", csv.path, p.Name(), p.String(), p.Image())
-		relatedImages = append(relatedImages, p.AsYamlObject())
-	}
+	relatedImages := []interface{}{}
 
-	spec, ok := csv.data.Object["spec"]
-	if !ok {
-		spec = map[string]interface{}{
-			"relatedImages": relatedImages,
-		}
-		csv.data.Object["spec"] = spec
-	} else {
-		spec.(map[string]interface{})["relatedImages"] = relatedImages
+	for _, entry := range entries {
+		p := entry.pullspec
+		log.Printf("%s - Set relateImage %s (from %s): %s\n", csv.path, entry.name, p.String(), p.Image())
+
+		obj := p.AsYamlObject()
+		obj["name"] = entry.name
+		relatedImages = append(relatedImages, obj)
 	}
 
-	return nil
+	defer csv.invalidate()
+
+	return relatedImagesLens.Set(csv.data.Object, relatedImages)
 }
 
 var knownAnnotationKeys = stringSlice{"containerImage"}
 
-func (csv *OperatorCSV) namedPullSpecs() ([]NamedPullSpec, error) {
+// NamedPullSpecs returns every pullspec found by the locators of the CSV, by
+// default the relatedImages, the containers and init containers of the
+// deployments, their RELATED_IMAGE_ env vars and the images found in
+// annotations. The order is stable and each pullspec has its kind, owner and
+// location set.
+func (csv *OperatorCSV) NamedPullSpecs() ([]NamedPullSpec, error) {
+	index := csv.index()
+
+	if index.named != nil {
+		return indexed(index.named, nil)
+	}
+
 	pullspecs := []NamedPullSpec{}
 
-	relatedImages, err := csv.relatedImagePullSpecs()
+	for _, locator := range csv.locators {
+		if locator.Name() == LocatorGuessedAnnotations && csv.Scope() == ScopeKnown {
+			continue
+		}
 
-	if err != nil {
-		return pullspecs, err
-	}
+		found, err := locator.Locate(csv)
 
-	containers, err := csv.containerPullSpecs()
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return pullspecs, err
+		pullspecs = append(pullspecs, found...)
 	}
 
-	initContainers, err := csv.initContainerPullSpecs()
+	pullspecs = uniquePullSpecs(pullspecs)
+	csv.attach(pullspecs)
 
-	if err != nil {
-		return pullspecs, err
+	paths := csv.pathIndex()
+	pullspecs = csv.excluded(pullspecs, paths)
+	csv.locate(pullspecs, paths)
+	setOwners(pullspecs, paths, csv.ownerOf)
+
+	for _, configMap := range csv.configMaps {
+		configMap.locate(pullspecs, configMap.pathIndex())
 	}
 
-	relatedImageEnvPullSpecs, err := csv.relatedImageEnvPullSpecs()
+	index.named = pullspecs
 
-	if err != nil {
-		return pullspecs, err
-	}
+	return indexed(pullspecs, nil)
+}
 
-	annotationPullSpecs, err := csv.annotationPullSpecs(knownAnnotationKeys)
+var deploymentsPath = []interface{}{"spec", "install", "spec", "deployments"}
 
-	if err != nil {
-		return pullspecs, err
+// ownerOf returns the deployment and container holding the value at path.
+func (csv *OperatorCSV) ownerOf(path []interface{}) Owner {
+	if len(path) <= len(deploymentsPath) || !reflect.DeepEqual(path[:len(deploymentsPath)], deploymentsPath) {
+		return Owner{}
 	}
 
-	guessedAnnotationPullSpecs, err := csv.annotationPullSpecs(nil)
+	deployments, err := csv.deployments()
+	i, ok := path[len(deploymentsPath)].(int)
 
-	if err != nil {
-		return pullspecs, err
+	if err != nil || !ok || i >= len(deployments) {
+		return Owner{}
 	}
 
-	pullspecs = append(pullspecs, relatedImages...)
-	pullspecs = append(pullspecs, containers...)
-	pullspecs = append(pullspecs, initContainers...)
-	pullspecs = append(pullspecs, relatedImageEnvPullSpecs...)
-	pullspecs = append(pullspecs, annotationPullSpecs...)
-	pullspecs = append(pullspecs, guessedAnnotationPullSpecs...)
+	deployment, _ := deployments[i].(map[string]interface{})
+	name, _ := deployment["name"].(string)
 
-	return pullspecs, nil
+	return Owner{
+		Deployment: name,
+		Container:  containerName(deployment, path[len(deploymentsPath)+1:]),
+	}
 }
 
 var relatedImagesLens = utils.Lens().M("spec").M("relatedImages").Build()
 
-func (csv *OperatorCSV) relatedImagePullSpecs() ([]NamedPullSpec, error) {
+// findRelatedImages returns the relatedImages of the CSV, see
+// relatedImagePullSpecs for the indexed ones.
+func (csv *OperatorCSV) findRelatedImages() ([]NamedPullSpec, error) {
 	lookupResultSlice, err := relatedImagesLens.L(csv.data.Object)
 
 	if err != nil {
@@ -662,93 +1003,33 @@ func (csv *OperatorCSV) deployments() ([]interface{}, error) {
 
 var initContainerLens = utils.Lens().M("spec").M("template").M("spec").M("initContainers").Build()
 
-func (csv *OperatorCSV) initContainerPullSpecs() ([]NamedPullSpec, error) {
-	deployments, err := csv.deployments()
-
-	if err != nil {
-		return nil, err
-	}
-
-	pullspecs := make([]NamedPullSpec, 0)
-
-	for i := range deployments {
-		lookupResultSlice, err := initContainerLens.L(deployments[i])
-		if err != nil {
-			if errors.Is(err, utils.ErrNotFound) {
-				continue
-			}
-
-			return nil, err
-		}
+var volumeLens = utils.Lens().M("spec").M("template").M("spec").M("volumes").Build()
 
-		for i := range lookupResultSlice {
-			data := lookupResultSlice[i]
-
-			pullspec, err := NewInitContainer(data)
-			if err != nil {
-				return nil, err
-			}
+// imageVolumes returns a pullspec for each image volume.
+func imageVolumes(volumes []interface{}) ([]NamedPullSpec, error) {
+	pullspecs := []NamedPullSpec{}
 
-			pullspecs = append(pullspecs, pullspec)
+	for i := range volumes {
+		if !isImageVolume(volumes[i]) {
+			continue
 		}
-	}
-
-	return pullspecs, nil
-}
-
-var containerLens = utils.Lens().M("spec").M("template").M("spec").M("containers").Build()
 
-func (csv *OperatorCSV) containerPullSpecs() ([]NamedPullSpec, error) {
-	deployments, err := csv.deployments()
-
-	if err != nil {
-		return nil, err
-	}
-
-	pullspecs := make([]NamedPullSpec, 0)
-
-	for i := range deployments {
-		lookupResultSlice, err := containerLens.L(deployments[i])
+		pullspec, err := NewImageVolume(volumes[i])
 
 		if err != nil {
-			if errors.Is(err, utils.ErrNotFound) {
-				continue
-			}
-
 			return nil, err
 		}
 
-		for i := range lookupResultSlice {
-			data := lookupResultSlice[i]
-
-			pullspec, err := NewContainer(data)
-
-			if err != nil {
-				return nil, err
-			}
-
-			pullspecs = append(pullspecs, pullspec)
-		}
+		pullspecs = append(pullspecs, pullspec)
 	}
 
 	return pullspecs, nil
 }
 
-func (csv *OperatorCSV) relatedImageEnvPullSpecs() ([]NamedPullSpec, error) {
-	containers, err := csv.containerPullSpecs()
-
-	if err != nil {
-		return nil, err
-	}
-
-	initContainers, err := csv.initContainerPullSpecs()
-
-	if err != nil {
-		return nil, err
-	}
-
-	allContainers := append(containers, initContainers...)
+var containerLens = utils.Lens().M("spec").M("template").M("spec").M("containers").Build()
 
+// findRelatedImageEnvs returns the RELATED_IMAGE_ env vars of the containers.
+func (csv *OperatorCSV) findRelatedImageEnvs(allContainers []NamedPullSpec) ([]NamedPullSpec, error) {
 	relatedImageEnvs := []NamedPullSpec{}
 
 	for i := range allContainers {
@@ -777,8 +1058,18 @@ func (csv *OperatorCSV) relatedImageEnvPullSpecs() ([]NamedPullSpec, error) {
 				continue
 			}
 
-			if _, hasValueFrom := envMap["valueFrom"]; hasValueFrom {
-				return nil, utils.NewError(nil, `%s: "valueFrom" references are not supported`, envMap["name"])
+			if valueFrom, hasValueFrom := envMap["valueFrom"]; hasValueFrom {
+				ps, err := csv.configMapPullSpec(envMap, valueFrom)
+
+				if err != nil {
+					return nil, err
+				}
+
+				if ps != nil {
+					relatedImageEnvs = append(relatedImageEnvs, ps)
+				}
+
+				continue
 			}
 
 			ps := NewRelatedImageEnv(envMap)
@@ -789,6 +1080,48 @@ func (csv *OperatorCSV) relatedImageEnvPullSpecs() ([]NamedPullSpec, error) {
 	return relatedImageEnvs, nil
 }
 
+var configMapKeyRefLens = utils.Lens().M("configMapKeyRef").Build()
+
+// configMapPullSpec follows the configMapKeyRef of an env var to the ConfigMap
+// holding its value. Optional references to missing keys are skipped.
+func (csv *OperatorCSV) configMapPullSpec(env map[string]interface{}, valueFrom interface{}) (NamedPullSpec, error) {
+	ref, err := configMapKeyRefLens.M(valueFrom)
+
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return nil, utils.NewError(nil, `%s: only "configMapKeyRef" "valueFrom" references are supported`, env["name"])
+		}
+
+		return nil, err
+	}
+
+	name, _ := ref["name"].(string)
+	key, _ := ref["key"].(string)
+
+	for _, configMap := range csv.configMaps {
+		if configMap.data.GetName() != name {
+			continue
+		}
+
+		data, ok := configMap.data.Object["data"].(map[string]interface{})
+
+		if !ok {
+			break
+		}
+
+		if _, ok := data[key].(string); ok {
+			return NewRelatedImageEnvFromConfigMap(env, data, key), nil
+		}
+	}
+
+	if optional, _ := ref["optional"].(bool); optional {
+		log.Printf("%s - skipping %s, the optional key %q of the ConfigMap %q is missing", csv.path, env["name"], key, name)
+		return nil, nil
+	}
+
+	return nil, utils.NewError(nil, `%s: key %q of the ConfigMap %q not found in the bundle`, env["name"], key, name)
+}
+
 func (csv *OperatorCSV) annotationPullSpecs(keyFilter stringSlice) ([]NamedPullSpec, error) {
 	pullSpecs := []NamedPullSpec{}
 
@@ -800,16 +1133,30 @@ func (csv *OperatorCSV) annotationPullSpecs(keyFilter stringSlice) ([]NamedPullS
 
 	for i := range annotationObjects {
 		obj := annotationObjects[i]
-		for rKey := range obj {
-			key := rKey
+		for _, key := range sortedKeys(obj) {
 			val := obj[key]
 
 			if keyFilter != nil && !keyFilter.Contains(key) {
 				continue
 			}
 
+			if key == SkipImagesAnnotation || key == SkipContainersAnnotation {
+				continue
+			}
+
 			valStr := fmt.Sprintf("%v", val)
-			results := csv.pullspecHeuristic(valStr)
+			var (
+				results [][]int
+				ok      bool
+			)
+
+			if key == almExamplesKey {
+				results, ok = almExamplesPullSpecs(valStr, csv.findPullSpecs)
+			}
+
+			if !ok {
+				results = csv.findPullSpecs(valStr)
+			}
 
 			for j := range results {
 				ii, jj := results[j][0], results[j][1]
@@ -830,7 +1177,9 @@ var (
 				Build()
 )
 
-func (csv *OperatorCSV) findAllAnnotations() ([]map[string]interface{}, error) {
+// collectAnnotations returns the annotation maps of the CSV, of its
+// deployments and of the objects it embeds.
+func (csv *OperatorCSV) collectAnnotations() ([]map[string]interface{}, error) {
 	findAnnotationMaps := []func() (map[string]interface{}, error){
 		csvAnnotations.MFunc(csv.data.Object),
 	}
@@ -891,7 +1240,7 @@ func (csv *OperatorCSV) findRandomCSVAnnotations(root map[string]interface{}, re
 		*results = append(*results, annos)
 	}
 
-	for key := range root {
+	for _, key := range sortedKeys(root) {
 		isUnderMetadata := false
 
 		if key == "metadata" {
@@ -928,20 +1277,25 @@ func (csv *OperatorCSV) findRandomCSVAnnotations(root map[string]interface{}, re
 }
 
 func (csv *OperatorCSV) findPotentialPullSpecsNotInAnnotations(root map[string]interface{}, specs *[]NamedPullSpec) error {
-	for rKey := range root {
-		key := rKey
-		val := root[key]
+	keys := sortedKeys(root)
+
+	for _, key := range keys {
+		valStr, ok := root[key].(string)
 
-		valStr := fmt.Sprintf("%v", val)
-		results := csv.pullspecHeuristic(valStr)
+		if !ok {
+			continue
+		}
 
-		for j := range results {
+		results := csv.findPullSpecs(valStr)
+
+		// last first, so replacing an image doesn't move the next ones
+		for j := len(results) - 1; j >= 0; j-- {
 			ii, jj := results[j][0], results[j][1]
 			*specs = append(*specs, NewAnnotation(root, key, ii, jj))
 		}
 	}
 
-	for key := range root {
+	for _, key := range keys {
 		if key == "metadata" {
 			continue
 		}
@@ -971,6 +1325,18 @@ func (csv *OperatorCSV) findPotentialPullSpecsNotInAnnotations(root map[string]i
 	return nil
 }
 
+// sortedKeys returns the keys of the map in order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
 type stringSlice []string
 
 func (l stringSlice) Contains(in string) bool {
@@ -982,6 +1348,53 @@ func (l stringSlice) Contains(in string) bool {
 	return false
 }
 
+// pullSpecID identifies the value, or the part of it, a pull spec refers to.
+type pullSpecID struct {
+	data  uintptr
+	key   string
+	start int
+	name  string
+}
+
+// uniquePullSpecs drops the pull specs referring to the same value as a
+// previous one, like annotations found by several lookups.
+func uniquePullSpecs(pullspecs []NamedPullSpec) []NamedPullSpec {
+	seen := map[pullSpecID]bool{}
+	unique := make([]NamedPullSpec, 0, len(pullspecs))
+
+	for _, ps := range pullspecs {
+		id := pullSpecID{data: mapID(ps.Data()), name: ps.Name()}
+
+		if l, ok := ps.(locatable); ok {
+			id.key = formatPath(l.imagePath())
+		}
+
+		if annotation, ok := ps.(*Annotation); ok {
+			id.start = annotation.startI
+		}
+
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		unique = append(unique, ps)
+	}
+
+	return unique
+}
+
+// containsImage returns true if one of the pull specs has the image.
+func containsImage(pullspecs []NamedPullSpec, image string) bool {
+	for _, ps := range pullspecs {
+		if ps.Image() == image {
+			return true
+		}
+	}
+
+	return false
+}
+
 type namedPullSpecSlice []NamedPullSpec
 
 func (n namedPullSpecSlice) Reverse() namedPullSpecSlice {
@@ -1,6 +1,9 @@
 package pullspec
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/operator-framework/operator-manifest-tools/internal/utils"
@@ -86,6 +89,45 @@ var _ = Describe("OperatorCSV", func() {
 		Expect(pullSpecs).To(ConsistOf(originalPullSpecs))
 	})
 
+	It("should list pullspecs in a stable order with their owners", func() {
+		csv, err := NewOperatorCSV("original.yaml", original.data, nil)
+		Expect(err).To(Succeed())
+
+		pullSpecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		for i := 0; i < 5; i++ {
+			again, err := csv.NamedPullSpecs()
+			Expect(err).To(Succeed())
+			Expect(again).To(HaveLen(len(pullSpecs)))
+
+			for j := range again {
+				Expect(again[j].Location()).To(Equal(pullSpecs[j].Location()))
+				Expect(again[j].Image()).To(Equal(pullSpecs[j].Image()))
+			}
+
+			images, err := csv.GetPullSpecs()
+			Expect(err).To(Succeed())
+			Expect(images[0]).To(Equal(pullSpecMap["ri1"].value))
+		}
+
+		owners := map[string]Owner{}
+		kinds := map[string]PullSpecKind{}
+		for _, ps := range pullSpecs {
+			owners[ps.Location().Path] = ps.Owner()
+			kinds[ps.Location().Path] = ps.Kind()
+		}
+
+		const deployments = "spec.install.spec.deployments"
+		Expect(kinds).To(HaveKeyWithValue(deployments+"[0].spec.template.spec.containers[0].image", KindContainer))
+		Expect(owners).To(HaveKeyWithValue(deployments+"[0].spec.template.spec.containers[0].image", Owner{Container: "c1"}))
+		Expect(kinds).To(HaveKeyWithValue(deployments+"[1].spec.template.spec.initContainers[0].env[0].value", KindRelatedImageEnv))
+		Expect(owners).To(HaveKeyWithValue(deployments+"[1].spec.template.spec.initContainers[0].env[0].value", Owner{Container: "ic1"}))
+		Expect(kinds).To(HaveKeyWithValue(deployments+"[0].spec.template.metadata.annotations.some_other_pullspec", KindAnnotation))
+		Expect(owners).To(HaveKeyWithValue(deployments+"[0].spec.template.metadata.annotations.some_other_pullspec", Owner{}))
+		Expect(owners).To(HaveKeyWithValue("spec.relatedImages[0].image", Owner{}))
+	})
+
 	It("should replace pullspecs", func() {
 		csv, err := NewOperatorCSV("original.yaml", original.data, nil)
 		Expect(err).To(Succeed())
@@ -115,6 +157,129 @@ var _ = Describe("OperatorCSV", func() {
 	})
 })
 
+var _ = Describe("RELATED_IMAGE_ env vars from ConfigMaps", func() {
+	var dir string
+
+	const digest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	csvWithRef := func(ref string) string {
+		return `kind: ClusterServiceVersion
+metadata:
+  name: csv
+spec:
+  install:
+    spec:
+      deployments:
+      - spec:
+          template:
+            spec:
+              containers:
+              - name: operator
+                image: registry.example.com/operator@` + digest + `
+                env:
+                - name: RELATED_IMAGE_OPERAND
+                  valueFrom:
+` + ref
+	}
+
+	const configMap = `kind: ConfigMap
+metadata:
+  name: images
+data:
+  operand: registry.example.com/operand:1.0 # pinned by the tool
+`
+
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(data), 0600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "configmaps")
+		Expect(err).To(Succeed())
+		write("configmap.yaml", configMap)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should follow configMapKeyRef references", func() {
+		write("csv.yaml", csvWithRef(`                    configMapKeyRef:
+                      name: images
+                      key: operand
+`))
+
+		csvs, err := FromDirectory(dir, nil)
+		Expect(err).To(Succeed())
+		csv := csvs[0]
+
+		pullSpecs, err := csv.GetPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(pullSpecs).To(ConsistOf(
+			imagename.Parse("registry.example.com/operator@"+digest),
+			imagename.Parse("registry.example.com/operand:1.0"),
+		))
+
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("registry.example.com/operand:1.0"): *imagename.Parse("registry.example.com/operand@" + digest),
+		})).To(Succeed())
+		Expect(csv.SetRelatedImages()).To(Succeed())
+		Expect(csv.Dump(nil)).To(Succeed())
+
+		b, err := os.ReadFile(filepath.Join(dir, "configmap.yaml"))
+		Expect(err).To(Succeed())
+		Expect(string(b)).To(Equal(strings.Replace(configMap, "operand:1.0", "operand@"+digest, 1)))
+
+		relatedImages, err := relatedImagesLens.L(csv.data.Object)
+		Expect(err).To(Succeed())
+		Expect(relatedImages).To(ContainElement(
+			map[string]interface{}{"name": "operand", "image": "registry.example.com/operand@" + digest},
+		))
+	})
+
+	It("should skip optional references to missing keys", func() {
+		write("csv.yaml", csvWithRef(`                    configMapKeyRef:
+                      name: images
+                      key: missing
+                      optional: true
+`))
+
+		csvs, err := FromDirectory(dir, nil)
+		Expect(err).To(Succeed())
+
+		pullSpecs, err := csvs[0].GetPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(pullSpecs).To(HaveLen(1))
+	})
+
+	It("should fail on references that can't be followed", func() {
+		write("csv.yaml", csvWithRef(`                    configMapKeyRef:
+                      name: other
+                      key: operand
+`))
+
+		csvs, err := FromDirectory(dir, nil)
+		Expect(err).To(Succeed())
+
+		_, err = csvs[0].GetPullSpecs()
+		Expect(err).To(MatchError(`RELATED_IMAGE_OPERAND: key "operand" of the ConfigMap "other" not found in the bundle`))
+
+		write("csv.yaml", csvWithRef(`                    secretKeyRef:
+                      name: images
+                      key: operand
+`))
+
+		csvs, err = FromDirectory(dir, nil)
+		Expect(err).To(Succeed())
+
+		_, err = csvs[0].GetPullSpecs()
+		Expect(err).To(MatchError(`RELATED_IMAGE_OPERAND: only "configMapKeyRef" "valueFrom" references are supported`))
+	})
+})
+
 type csvFile struct {
 	data *unstructured.Unstructured
 }
@@ -454,3 +619,133 @@ random:
     - {.ic1.Replace}
     - {.ice1.Replace}
 `
+
+var _ = Describe("Image volumes", func() {
+	const src = `kind: ClusterServiceVersion
+spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+              volumes:
+              - name: model
+                image:
+                  reference: registry.example.com/team/model:1
+                  pullPolicy: IfNotPresent
+              - name: config
+                configMap:
+                  name: config
+`
+
+	It("should find, replace and relate the image volumes", func() {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err := NewOperatorCSV("csv.yaml", data, nil)
+		Expect(err).To(Succeed())
+
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(pullspecs).To(HaveLen(2))
+
+		volume := pullspecs[1]
+		Expect(volume.Kind()).To(Equal(KindImageVolume))
+		Expect(volume.Name()).To(Equal("model"))
+		Expect(volume.Owner()).To(Equal(Owner{Deployment: "operator"}))
+		Expect(volume.Location().Path).To(Equal("spec.install.spec.deployments[0].spec.template.spec.volumes[0].image.reference"))
+
+		Expect(csv.ReplacePullSpecsEverywhere(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("registry.example.com/team/model:1"): *imagename.Parse("registry.example.com/team/model@sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+		})).To(Succeed())
+		Expect(volume.Data()).To(Equal(map[string]interface{}{
+			"reference":  "registry.example.com/team/model@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+			"pullPolicy": "IfNotPresent",
+		}))
+
+		Expect(csv.SetRelatedImages()).To(Succeed())
+		relatedImages, err := relatedImagesLens.L(csv.data.Object)
+		Expect(err).To(Succeed())
+		Expect(relatedImages).To(ContainElement(map[string]interface{}{
+			"name": "model", "image": "registry.example.com/team/model@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		}))
+	})
+
+	It("should find the image volumes of workload manifests", func() {
+		manifest := NewManifest("pod.yaml", &unstructured.Unstructured{Object: map[string]interface{}{
+			"kind":     "Pod",
+			"metadata": map[string]interface{}{"name": "pod"},
+			"spec": map[string]interface{}{
+				"containers": []interface{}{},
+				"volumes": []interface{}{
+					map[string]interface{}{"name": "plugin", "image": map[string]interface{}{"reference": "plugin"}},
+				},
+			},
+		}}, nil)
+
+		pullspecs, err := manifest.NamedPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(pullspecs).To(HaveLen(1))
+		Expect(pullspecs[0].Kind()).To(Equal(KindImageVolume))
+		Expect(pullspecs[0].Name()).To(Equal("pod-plugin"))
+		Expect(pullspecs[0].Image()).To(Equal("plugin"))
+	})
+})
+
+var _ = Describe("Malformed pullspecs", func() {
+	const src = `kind: ClusterServiceVersion
+metadata:
+  annotations:
+    description: Runs Quay.io/Team/App:1 and quay.io/a/b/c:1
+spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: %s
+`
+
+	It("should report malformed images", func() {
+		csv, err := decodeCSV(fmt.Sprintf(src, "quay.io/team/Operator:1"))
+		Expect(err).To(Succeed())
+
+		_, err = csv.GetPullSpecs()
+		Expect(err).To(MatchError(imagename.ErrNameContainsUppercase))
+		Expect(err).To(MatchError(ContainSubstring("invalid pullspec for container manager")))
+
+		Expect(csv.ReplacePullSpecs(map[imagename.ImageName]imagename.ImageName{})).
+			To(MatchError(imagename.ErrNameContainsUppercase))
+	})
+
+	It("should ignore malformed guesses and keep nested namespaces", func() {
+		csv, err := decodeCSV(fmt.Sprintf(src, "quay.io/a/b/operator:1"))
+		Expect(err).To(Succeed())
+
+		images, err := csv.GetPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(images).To(ConsistOf(
+			imagename.Parse("quay.io/a/b/operator:1"),
+			imagename.Parse("quay.io/a/b/c:1"),
+		))
+
+		old, err := imagename.ParseStrict("quay.io/a/b/c:1")
+		Expect(err).To(Succeed())
+		new, err := imagename.ParseStrict("quay.io/a/b/c:2")
+		Expect(err).To(Succeed())
+
+		Expect(csv.ReplacePullSpecsEverywhere(map[imagename.ImageName]imagename.ImageName{*old: *new})).To(Succeed())
+		Expect(csv.data.GetAnnotations()["description"]).To(Equal("Runs Quay.io/Team/App:1 and quay.io/a/b/c:2"))
+	})
+})
@@ -0,0 +1,342 @@
+package pullspec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+// RelatedImagesPolicy decides what happens to the entries already in the CSV
+// relatedImages when they are set again.
+type RelatedImagesPolicy string
+
+const (
+	// RelatedImagesPreserve keeps every existing relatedImages entry.
+	RelatedImagesPreserve RelatedImagesPolicy = "preserve"
+	// RelatedImagesPrune keeps the existing relatedImages entries whose image is
+	// still referenced by a container, an env var or an annotation.
+	RelatedImagesPrune RelatedImagesPolicy = "prune"
+	// RelatedImagesReplace drops every existing relatedImages entry, the
+	// relatedImages are rebuilt from the other pullspecs only.
+	RelatedImagesReplace RelatedImagesPolicy = "replace"
+)
+
+// RelatedImagesPolicies lists the valid relatedImages policies.
+var RelatedImagesPolicies = []RelatedImagesPolicy{
+	RelatedImagesPreserve,
+	RelatedImagesPrune,
+	RelatedImagesReplace,
+}
+
+// ParseRelatedImagesPolicy returns the relatedImages policy named policy.
+func ParseRelatedImagesPolicy(policy string) (RelatedImagesPolicy, error) {
+	for _, valid := range RelatedImagesPolicies {
+		if string(valid) == policy {
+			return valid, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid relatedImages policy %q, valid values are %v", policy, RelatedImagesPolicies)
+}
+
+// RelatedImagesOption configures how the relatedImages are set.
+type RelatedImagesOption func(*relatedImagesOptions)
+
+type relatedImagesOptions struct {
+	policy       RelatedImagesPolicy
+	nameTemplate *template.Template
+	conflicts    ConflictStrategy
+}
+
+// WithRelatedImagesPolicy sets the policy applied to the existing relatedImages.
+// By default they are preserved.
+func WithRelatedImagesPolicy(policy RelatedImagesPolicy) RelatedImagesOption {
+	return func(opts *relatedImagesOptions) {
+		opts.policy = policy
+	}
+}
+
+// WithRelatedImagesNameTemplate names the relatedImages entries with the
+// template, executed with a RelatedImageNameData. The names are then made
+// valid DNS-1123 labels, like the default ones. Existing relatedImages entries
+// keep their name.
+func WithRelatedImagesNameTemplate(nameTemplate *template.Template) RelatedImagesOption {
+	return func(opts *relatedImagesOptions) {
+		opts.nameTemplate = nameTemplate
+	}
+}
+
+// WithConflictStrategy sets how relatedImages entries sharing a name but not an
+// image are handled. By default setting the relatedImages fails.
+func WithConflictStrategy(strategy ConflictStrategy) RelatedImagesOption {
+	return func(opts *relatedImagesOptions) {
+		opts.conflicts = strategy
+	}
+}
+
+func newRelatedImagesOptions(opts []RelatedImagesOption) relatedImagesOptions {
+	options := relatedImagesOptions{policy: RelatedImagesPreserve, conflicts: ConflictFail}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// filter applies the policy to the relatedImages pullspecs.
+func (policy RelatedImagesPolicy) filter(pullspecs []NamedPullSpec) []NamedPullSpec {
+	if policy == RelatedImagesPreserve {
+		return pullspecs
+	}
+
+	referenced := map[imagename.ImageName]bool{}
+
+	for _, ps := range pullspecs {
+		if ps.Kind() != KindRelatedImage {
+			referenced[imagename.Parse(ps.Image()).Canonical()] = true
+		}
+	}
+
+	filtered := make([]NamedPullSpec, 0, len(pullspecs))
+
+	for _, ps := range pullspecs {
+		if ps.Kind() == KindRelatedImage &&
+			(policy == RelatedImagesReplace || !referenced[imagename.Parse(ps.Image()).Canonical()]) {
+			continue
+		}
+
+		filtered = append(filtered, ps)
+	}
+
+	return filtered
+}
+
+// RelatedImageNameData is the data the relatedImages name template is executed with.
+type RelatedImageNameData struct {
+	// Name is the default name of the entry.
+	Name string
+	// Kind is the kind of field the image was found in.
+	Kind PullSpecKind
+	// Deployment and Container are the owner of the image, if any.
+	Deployment string
+	Container  string
+	// Registry, Namespace, Repo and Tag are the parts of the image.
+	Registry  string
+	Namespace string
+	Repo      string
+	Tag       string
+}
+
+const maxNameLength = 63
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sanitizeName turns name into a valid DNS-1123 label.
+func sanitizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+
+	return strings.Trim(name, "-")
+}
+
+// name returns the name of the relatedImages entry of the pullspec. The names
+// are made valid DNS-1123 labels, existing entries keep their name.
+func (opts *relatedImagesOptions) name(ps NamedPullSpec) (string, error) {
+	if ps.Kind() == KindRelatedImage {
+		return ps.Name(), nil
+	}
+
+	if opts.nameTemplate == nil {
+		if name := sanitizeName(ps.Name()); name != "" {
+			return name, nil
+		}
+
+		return "", fmt.Errorf("the relatedImage name of %s is empty", ps.String())
+	}
+
+	image := imagename.Parse(ps.Image())
+	data := RelatedImageNameData{
+		Name:       ps.Name(),
+		Kind:       ps.Kind(),
+		Deployment: ps.Owner().Deployment,
+		Container:  ps.Owner().Container,
+		Registry:   image.Registry,
+		Namespace:  image.Namespace,
+		Repo:       image.Repo,
+		Tag:        image.Tag,
+	}
+
+	b := strings.Builder{}
+
+	if err := opts.nameTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to name the relatedImage of %s: %w", ps.String(), err)
+	}
+
+	name := sanitizeName(b.String())
+
+	if name == "" {
+		return "", fmt.Errorf("the relatedImage name of %s is empty", ps.String())
+	}
+
+	return name, nil
+}
+
+// ConflictStrategy decides what happens when relatedImages entries share a
+// name but not an image.
+type ConflictStrategy struct {
+	mode   string
+	prefer PullSpecKind
+}
+
+var (
+	// ConflictFail fails to set the relatedImages.
+	ConflictFail = ConflictStrategy{mode: "fail"}
+	// ConflictSuffix keeps the first entry and suffixes the name of the others
+	// with -2, -3, ... The names are trimmed to fit the suffix.
+	ConflictSuffix = ConflictStrategy{mode: "suffix"}
+)
+
+// ConflictPrefer keeps the entry found in a field of the given kind. Setting
+// the relatedImages fails if there isn't a single image of that kind.
+func ConflictPrefer(kind PullSpecKind) ConflictStrategy {
+	return ConflictStrategy{mode: "prefer", prefer: kind}
+}
+
+var pullSpecKinds = []PullSpecKind{
+	KindContainer, KindInitContainer, KindRelatedImage, KindRelatedImageEnv, KindAnnotation,
+	KindImageVolume, KindContainerArg, KindImageEnv, KindPath,
+}
+
+// ParseConflictStrategy parses a conflict strategy, one of fail, suffix or
+// prefer=<kind> where kind is a PullSpecKind like container.
+func ParseConflictStrategy(strategy string) (ConflictStrategy, error) {
+	switch strategy {
+	case ConflictFail.String():
+		return ConflictFail, nil
+	case ConflictSuffix.String():
+		return ConflictSuffix, nil
+	}
+
+	if kind := strings.TrimPrefix(strategy, "prefer="); kind != strategy {
+		for _, valid := range pullSpecKinds {
+			if string(valid) == kind {
+				return ConflictPrefer(valid), nil
+			}
+		}
+
+		return ConflictStrategy{}, fmt.Errorf("invalid pullspec kind %q, valid values are %v", kind, pullSpecKinds)
+	}
+
+	return ConflictStrategy{}, fmt.Errorf("invalid conflict strategy %q, valid values are fail, suffix or prefer=<kind>", strategy)
+}
+
+// String returns a string representation of the strategy.
+func (strategy ConflictStrategy) String() string {
+	if strategy.mode == "prefer" {
+		return "prefer=" + string(strategy.prefer)
+	}
+
+	return strategy.mode
+}
+
+// namedEntry is a relatedImages entry and the pullspec it comes from. Entries
+// renamed on conflicts keep the name they conflicted on and their suffix, so
+// they sort like x, x-2, ..., x-10.
+type namedEntry struct {
+	name     string
+	base     string
+	suffix   int
+	pullspec NamedPullSpec
+}
+
+// less sorts the entries by name, suffixed entries following the entry they
+// conflicted with.
+func (entry namedEntry) less(other namedEntry) bool {
+	if entry.base != other.base {
+		return entry.base < other.base
+	}
+
+	return entry.suffix < other.suffix
+}
+
+// suffixName appends -n to the name, trimming the name so the result stays
+// within maxNameLength.
+func suffixName(name string, n int) string {
+	suffix := fmt.Sprintf("-%d", n)
+
+	if len(name)+len(suffix) > maxNameLength {
+		name = strings.TrimRight(name[:maxNameLength-len(suffix)], "-")
+	}
+
+	return name + suffix
+}
+
+// resolve names the relatedImages entries of the pullspecs, grouped by
+// name in the order they were found. Entries with the same name and image are
+// merged, the other conflicts are resolved by the strategy or returned.
+func (strategy ConflictStrategy) resolve(names []string, groups map[string][]NamedPullSpec) ([]namedEntry, []string) {
+	taken := map[string]bool{}
+
+	for _, name := range names {
+		taken[name] = true
+	}
+
+	entries := []namedEntry{}
+	conflicts := []string{}
+
+	for _, name := range names {
+		group := groups[name]
+
+		if len(group) == 1 {
+			entries = append(entries, namedEntry{name: name, base: name, pullspec: group[0]})
+			continue
+		}
+
+		switch strategy.mode {
+		case "suffix":
+			entries = append(entries, namedEntry{name: name, base: name, pullspec: group[0]})
+
+			for i, n := 1, 2; i < len(group); n++ {
+				suffixed := suffixName(name, n)
+
+				if taken[suffixed] {
+					continue
+				}
+
+				taken[suffixed] = true
+				entries = append(entries, namedEntry{name: suffixed, base: name, suffix: n, pullspec: group[i]})
+				i++
+			}
+
+			continue
+		case "prefer":
+			preferred := []NamedPullSpec{}
+
+			for _, ps := range group {
+				if ps.Kind() == strategy.prefer {
+					preferred = append(preferred, ps)
+				}
+			}
+
+			if len(preferred) == 1 {
+				entries = append(entries, namedEntry{name: name, base: name, pullspec: preferred[0]})
+				continue
+			}
+		}
+
+		old := group[0]
+		for _, newPull := range group[1:] {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s X %s: %s",
+				old.String(), old.Image(), newPull.String(), newPull.Image()))
+		}
+	}
+
+	return entries, conflicts
+}
@@ -0,0 +1,229 @@
+package pullspec
+
+import (
+	"sort"
+	"strings"
+	"text/template"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("SetRelatedImages", func() {
+	const src = `kind: ClusterServiceVersion
+spec:
+  relatedImages:
+  - name: stale
+    image: registry.example.com/stale:1
+  - name: zz-operator
+    image: registry.example.com/operator:1
+    extra: kept
+  install:
+    spec:
+      deployments:
+      - spec:
+          template:
+            spec:
+              containers:
+              - name: operator
+                image: registry.example.com/operator:1
+              - name: b-operand
+                image: registry.example.com/operand:1
+`
+
+	relatedImages := func(opts ...RelatedImagesOption) []interface{} {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err := NewOperatorCSV("csv.yaml", data, nil)
+		Expect(err).To(Succeed())
+		Expect(csv.SetRelatedImages(opts...)).To(Succeed())
+
+		result, err := relatedImagesLens.L(csv.data.Object)
+		Expect(err).To(Succeed())
+		return result
+	}
+
+	var (
+		operand  = map[string]interface{}{"name": "b-operand", "image": "registry.example.com/operand:1"}
+		operator = map[string]interface{}{"name": "operator", "image": "registry.example.com/operator:1"}
+		stale    = map[string]interface{}{"name": "stale", "image": "registry.example.com/stale:1"}
+		existing = map[string]interface{}{"name": "zz-operator", "image": "registry.example.com/operator:1", "extra": "kept"}
+	)
+
+	DescribeTable("policies",
+		func(opts []RelatedImagesOption, expected []interface{}) {
+			for i := 0; i < 5; i++ {
+				Expect(relatedImages(opts...)).To(Equal(expected))
+			}
+		},
+		Entry("preserve by default", nil, []interface{}{operand, operator, stale, existing}),
+		Entry("preserve", []RelatedImagesOption{WithRelatedImagesPolicy(RelatedImagesPreserve)}, []interface{}{operand, operator, stale, existing}),
+		Entry("prune", []RelatedImagesOption{WithRelatedImagesPolicy(RelatedImagesPrune)}, []interface{}{operand, operator, existing}),
+		Entry("replace", []RelatedImagesOption{WithRelatedImagesPolicy(RelatedImagesReplace)}, []interface{}{operand, operator}),
+	)
+
+	It("should parse policies", func() {
+		policy, err := ParseRelatedImagesPolicy("prune")
+		Expect(err).To(Succeed())
+		Expect(policy).To(Equal(RelatedImagesPrune))
+
+		_, err = ParseRelatedImagesPolicy("other")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("relatedImages names", func() {
+	const src = `kind: ClusterServiceVersion
+spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+                env:
+                - name: RELATED_IMAGE_MANAGER
+                  value: registry.example.com/team/operand:1
+              initContainers:
+              - name: manager
+                image: registry.example.com/team/init:1
+`
+
+	relatedImages := func(opts ...RelatedImagesOption) ([]interface{}, error) {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err := NewOperatorCSV("csv.yaml", data, nil)
+		Expect(err).To(Succeed())
+
+		if err := csv.SetRelatedImages(opts...); err != nil {
+			return nil, err
+		}
+
+		return relatedImagesLens.L(csv.data.Object)
+	}
+
+	byContainer := WithRelatedImagesNameTemplate(template.Must(template.New("name").Parse("{{.Deployment}}_{{.Container}}")))
+
+	It("should name the entries with the template", func() {
+		result, err := relatedImages(WithRelatedImagesNameTemplate(template.Must(template.New("name").Parse("{{.Repo}} {{.Kind}}"))))
+		Expect(err).To(Succeed())
+		Expect(result).To(Equal([]interface{}{
+			map[string]interface{}{"name": "init-initcontainer", "image": "registry.example.com/team/init:1"},
+			map[string]interface{}{"name": "operand-relatedimageenv", "image": "registry.example.com/team/operand:1"},
+			map[string]interface{}{"name": "operator-container", "image": "registry.example.com/team/operator:1"},
+		}))
+	})
+
+	It("should make the default names valid", func() {
+		csv, err := decodeCSV(`kind: ClusterServiceVersion
+metadata:
+  annotations:
+    containerImage: registry.example.com/b/c:v1.2
+spec:
+  install:
+    spec:
+      deployments: []
+`)
+		Expect(err).To(Succeed())
+		Expect(csv.SetRelatedImages()).To(Succeed())
+
+		result, err := relatedImagesLens.L(csv.data.Object)
+		Expect(err).To(Succeed())
+		Expect(result).To(Equal([]interface{}{
+			map[string]interface{}{"name": "c-v1-2-annotation", "image": "registry.example.com/b/c:v1.2"},
+		}))
+	})
+
+	It("should fail on conflicts by default", func() {
+		_, err := relatedImages(byContainer)
+		Expect(err).To(MatchError(ContainSubstring("Found conflicts when setting relatedImages")))
+	})
+
+	It("should suffix conflicting names", func() {
+		result, err := relatedImages(byContainer, WithConflictStrategy(ConflictSuffix))
+		Expect(err).To(Succeed())
+		Expect(result).To(Equal([]interface{}{
+			map[string]interface{}{"name": "operator-manager", "image": "registry.example.com/team/operator:1"},
+			map[string]interface{}{"name": "operator-manager-2", "image": "registry.example.com/team/init:1"},
+			map[string]interface{}{"name": "operator-manager-3", "image": "registry.example.com/team/operand:1"},
+		}))
+	})
+
+	It("should keep suffixed names within the length limit", func() {
+		long := strings.Repeat("a", 62)
+		result, err := relatedImages(WithRelatedImagesNameTemplate(template.Must(template.New("name").Parse(long))),
+			WithConflictStrategy(ConflictSuffix))
+		Expect(err).To(Succeed())
+
+		names := []interface{}{}
+		for _, entry := range result {
+			names = append(names, entry.(map[string]interface{})["name"])
+		}
+
+		Expect(names).To(Equal([]interface{}{long, long[:61] + "-2", long[:61] + "-3"}))
+	})
+
+	It("should sort suffixed names after the name they conflicted on", func() {
+		entries := []namedEntry{
+			{name: "x-10", base: "x", suffix: 10},
+			{name: "x-2", base: "x", suffix: 2},
+			{name: "y", base: "y"},
+			{name: "x", base: "x"},
+		}
+
+		sort.Slice(entries, func(i, j int) bool { return entries[i].less(entries[j]) })
+
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.name)
+		}
+
+		Expect(names).To(Equal([]string{"x", "x-2", "x-10", "y"}))
+	})
+
+	It("should prefer an image of a kind", func() {
+		result, err := relatedImages(byContainer, WithConflictStrategy(ConflictPrefer(KindInitContainer)))
+		Expect(err).To(Succeed())
+		Expect(result).To(Equal([]interface{}{
+			map[string]interface{}{"name": "operator-manager", "image": "registry.example.com/team/init:1"},
+		}))
+
+		_, err = relatedImages(byContainer, WithConflictStrategy(ConflictPrefer(KindAnnotation)))
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("sanitizeName",
+		func(name, expected string) {
+			Expect(sanitizeName(name)).To(Equal(expected))
+		},
+		Entry("lowercase", "Operator", "operator"),
+		Entry("invalid characters", "-my_image..name-", "my-image-name"),
+		Entry("too long", "a234567890b234567890c234567890d234567890e234567890f234567890g2-xyz", "a234567890b234567890c234567890d234567890e234567890f234567890g2"),
+	)
+
+	It("should parse conflict strategies", func() {
+		for _, valid := range []string{"fail", "suffix", "prefer=container"} {
+			strategy, err := ParseConflictStrategy(valid)
+			Expect(err).To(Succeed())
+			Expect(strategy.String()).To(Equal(valid))
+		}
+
+		_, err := ParseConflictStrategy("prefer=other")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseConflictStrategy("other")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,181 @@
+package pullspec
+
+import "github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+
+// The scanner finds the same pullspecs as the pullspec regex, see
+// DefaultHeuristic, in a single pass over the text without allocating for
+// every candidate.
+
+// isCandidateByte returns true if c may appear in a pullspec, it's the
+// character class of the candidate regex.
+func isCandidateByte(c byte) bool {
+	return isNameByte(c) || c == '/' || c == '@' || c == ':'
+}
+
+// isAlnumByte matches the alnum rule.
+func isAlnumByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// isNameByte matches the name rule.
+func isNameByte(c byte) bool {
+	return isAlnumByte(c) || c == '-' || c == '.' || c == '_'
+}
+
+// isDigitByte returns true if c is a decimal digit.
+func isDigitByte(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isBase16Byte matches the base16 rule.
+func isBase16Byte(c byte) bool {
+	return isDigitByte(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// scanPullSpecs returns the bounds of the pullspecs in text. Candidates are
+// the longest runs of pullspec characters, stripped of the non alphanumeric
+// characters around them, that match the pullspec rule as a whole.
+func scanPullSpecs(text string) [][]int {
+	pullspecs := [][]int{}
+
+	for i := 0; i < len(text); {
+		if !isCandidateByte(text[i]) {
+			i++
+			continue
+		}
+
+		start := i
+
+		for i < len(text) && isCandidateByte(text[i]) {
+			i++
+		}
+
+		// candidates only hold ascii characters
+		s, e := start, i
+
+		for s < e && !isAlnumByte(text[s]) {
+			s++
+		}
+
+		for e > s && !isAlnumByte(text[e-1]) {
+			e--
+		}
+
+		if s != e && isPullSpec(text[s:e]) {
+			pullspecs = append(pullspecs, []int{s, e})
+		}
+	}
+
+	return pullspecs
+}
+
+// isPullSpec returns true if s matches the pullspec rule:
+// registry/namespace*/repo, the repo being followed by a tag or a digest.
+func isPullSpec(s string) bool {
+	end := indexByte(s, 0, '/')
+
+	if end < 0 || !isRegistry(s[:end]) {
+		return false
+	}
+
+	for {
+		start := end + 1
+		end = indexByte(s, start, '/')
+
+		if end < 0 {
+			return isRepo(s[start:])
+		}
+
+		if !isBasicName(s[start:end]) {
+			return false
+		}
+	}
+}
+
+// indexByte returns the index of the first c in s from start, or -1.
+func indexByte(s string, start int, c byte) int {
+	for i := start; i < len(s); i++ {
+		if s[i] == c {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// isBasicName matches the basicName rule: name characters starting and
+// ending with an alphanumeric character.
+func isBasicName(s string) bool {
+	if s == "" || !isAlnumByte(s[0]) || !isAlnumByte(s[len(s)-1]) {
+		return false
+	}
+
+	for i := 1; i < len(s)-1; i++ {
+		if !isNameByte(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isRegistry matches the registry rule: a basic name holding a dot that isn't
+// its first nor its last character, followed by an optional port.
+func isRegistry(s string) bool {
+	if colon := indexByte(s, 0, ':'); colon >= 0 {
+		port := s[colon+1:]
+
+		if port == "" {
+			return false
+		}
+
+		for i := 0; i < len(port); i++ {
+			if !isDigitByte(port[i]) {
+				return false
+			}
+		}
+
+		s = s[:colon]
+	}
+
+	return len(s) >= 3 && isBasicName(s) && indexByte(s[1:len(s)-1], 0, '.') >= 0
+}
+
+// isRepo matches the repo rule: a basic name followed by :tag or
+// @algorithm:digest.
+func isRepo(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ':':
+			return isBasicName(s[:i]) && isBasicName(s[i+1:])
+		case '@':
+			return isBasicName(s[:i]) && isDigest(s[i+1:])
+		}
+	}
+
+	return false
+}
+
+// isDigest matches a digest of a supported algorithm made of exactly as many
+// base16 characters as the algorithm needs, see imagename.DigestLength.
+func isDigest(s string) bool {
+	colon := indexByte(s, 0, ':')
+
+	if colon < 0 {
+		return false
+	}
+
+	length, ok := imagename.DigestLength(s[:colon])
+
+	if !ok || len(s) != colon+1+length {
+		return false
+	}
+
+	for i := colon + 1; i < len(s); i++ {
+		if !isBase16Byte(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
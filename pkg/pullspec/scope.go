@@ -0,0 +1,146 @@
+package pullspec
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+)
+
+// Scope sets which strings of the manifests are scanned for images.
+type Scope string
+
+const (
+	// ScopeKnown only uses the fields known to hold images, like the container
+	// images, the relatedImages or the containerImage annotation.
+	ScopeKnown Scope = "known"
+	// ScopeAnnotations uses the known fields and the images the heuristic
+	// finds in annotations.
+	ScopeAnnotations Scope = "annotations"
+	// ScopeEverywhere uses the known fields and the images the heuristic finds
+	// in any string, like the CSV description. It's the default.
+	ScopeEverywhere Scope = "everywhere"
+)
+
+// Scopes are the valid scopes.
+var Scopes = []Scope{ScopeKnown, ScopeAnnotations, ScopeEverywhere}
+
+// ParseScope returns the scope named scope.
+func ParseScope(scope string) (Scope, error) {
+	for _, valid := range Scopes {
+		if string(valid) == scope {
+			return valid, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid scope %q, valid values are %v", scope, Scopes)
+}
+
+// SetScope sets which strings of the document are scanned for images.
+func (doc *document) SetScope(scope Scope) {
+	doc.scope = scope
+	doc.invalidate()
+}
+
+// Scope returns which strings of the document are scanned for images.
+func (doc *document) Scope() Scope {
+	if doc.scope == "" {
+		return ScopeEverywhere
+	}
+
+	return doc.scope
+}
+
+// SetExcludePaths sets the paths of the document where images are ignored,
+// like spec.description or spec.install.spec.deployments[*].spec.template.spec.containers[?name=sample].
+// Everything under a path is ignored. See utils.ParsePath for the syntax.
+func (doc *document) SetExcludePaths(paths ...string) error {
+	excludes := make([]func(interface{}, []interface{}) bool, 0, len(paths))
+
+	for _, path := range paths {
+		l, err := utils.ParsePath(path)
+
+		if err != nil {
+			return err
+		}
+
+		excludes = append(excludes, l.MatchesPath)
+	}
+
+	doc.excludes = excludes
+	doc.invalidate()
+	return nil
+}
+
+// excluded drops the pull specs of the document under one of its excluded
+// paths.
+func (doc *document) excluded(pullspecs []NamedPullSpec, paths map[uintptr][]interface{}) []NamedPullSpec {
+	if len(doc.excludes) == 0 {
+		return pullspecs
+	}
+
+	kept := make([]NamedPullSpec, 0, len(pullspecs))
+
+	for _, ps := range pullspecs {
+		if doc.isExcluded(ps, paths) {
+			continue
+		}
+
+		kept = append(kept, ps)
+	}
+
+	return kept
+}
+
+func (doc *document) isExcluded(ps NamedPullSpec, paths map[uintptr][]interface{}) bool {
+	l, ok := ps.(locatable)
+	path, found := paths[mapID(ps.Data())]
+
+	if !ok || !found {
+		return false
+	}
+
+	path = append(path[:len(path):len(path)], l.imagePath()...)
+
+	for _, matches := range doc.excludes {
+		if matches(doc.data.Object, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScanConfig sets where images are looked for in the manifests.
+type ScanConfig struct {
+	// Scope sets which strings are scanned for images, everywhere by default.
+	Scope Scope
+	// ExcludePaths are the paths where images are ignored.
+	ExcludePaths []string
+	// Suppress are the strings the heuristic must not report as images, see
+	// Suppressions.
+	Suppress []string
+}
+
+// Apply sets the scan config on the CSV.
+func (config *ScanConfig) Apply(csv *OperatorCSV) error {
+	return config.apply(csv.document)
+}
+
+// apply sets the scan config on the document.
+func (config *ScanConfig) apply(doc *document) error {
+	if config.Scope != "" {
+		doc.SetScope(config.Scope)
+	}
+
+	if len(config.Suppress) != 0 {
+		suppressions, err := NewSuppressions(config.Suppress...)
+
+		if err != nil {
+			return err
+		}
+
+		doc.SetSuppressions(suppressions)
+	}
+
+	return doc.SetExcludePaths(config.ExcludePaths...)
+}
@@ -0,0 +1,131 @@
+package pullspec
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Scope", func() {
+	const src = `kind: ClusterServiceVersion
+metadata:
+  annotations:
+    containerImage: registry.example.com/team/operator:1
+    example.com/sample: registry.example.com/team/sample:1
+spec:
+  description: Run registry.example.com/team/operator:1 or registry.example.com/team/sample:1
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+              - name: sample
+                image: registry.example.com/team/sample:1
+`
+
+	var csv *OperatorCSV
+
+	BeforeEach(func() {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err = NewOperatorCSV("csv.yaml", data, nil)
+		Expect(err).To(Succeed())
+	})
+
+	paths := func() []string {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		result := []string{}
+		for _, ps := range pullspecs {
+			result = append(result, ps.Location().Path)
+		}
+		return result
+	}
+
+	replace := func() map[string]interface{} {
+		Expect(csv.ReplacePullSpecsEverywhere(map[imagename.ImageName]imagename.ImageName{
+			*imagename.Parse("registry.example.com/team/operator:1"): *imagename.Parse("registry.example.com/team/operator@sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+			*imagename.Parse("registry.example.com/team/sample:1"):   *imagename.Parse("registry.example.com/team/sample@sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+		})).To(Succeed())
+
+		return csv.data.Object
+	}
+
+	description := func(obj map[string]interface{}) interface{} {
+		return obj["spec"].(map[string]interface{})["description"]
+	}
+
+	sample := func(obj map[string]interface{}) interface{} {
+		return obj["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["example.com/sample"]
+	}
+
+	DescribeTable("replacing",
+		func(scope Scope, expectedDescription, expectedSample string) {
+			if scope != "" {
+				csv.SetScope(scope)
+			}
+
+			obj := replace()
+			Expect(description(obj)).To(Equal(expectedDescription))
+			Expect(sample(obj)).To(Equal(expectedSample))
+		},
+		Entry("everywhere by default", Scope(""),
+			"Run registry.example.com/team/operator@sha256:1111111111111111111111111111111111111111111111111111111111111111 or registry.example.com/team/sample@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+			"registry.example.com/team/sample@sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+		Entry("known", ScopeKnown,
+			"Run registry.example.com/team/operator:1 or registry.example.com/team/sample:1",
+			"registry.example.com/team/sample:1"),
+		Entry("annotations", ScopeAnnotations,
+			"Run registry.example.com/team/operator:1 or registry.example.com/team/sample:1",
+			"registry.example.com/team/sample@sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+	)
+
+	It("should only extract the known fields", func() {
+		Expect(paths()).To(ContainElement(`metadata.annotations["example.com/sample"]`))
+
+		csv.SetScope(ScopeKnown)
+		Expect(paths()).To(Equal([]string{
+			"spec.install.spec.deployments[0].spec.template.spec.containers[0].image",
+			"spec.install.spec.deployments[0].spec.template.spec.containers[1].image",
+			"metadata.annotations.containerImage",
+		}))
+	})
+
+	It("should exclude paths", func() {
+		Expect(csv.SetExcludePaths(
+			"spec.description",
+			"spec.install.spec.deployments[*].spec.template.spec.containers[?name=sample]",
+			`metadata.annotations["example.com/sample"]`,
+		)).To(Succeed())
+
+		Expect(paths()).To(Equal([]string{
+			"spec.install.spec.deployments[0].spec.template.spec.containers[0].image",
+			"metadata.annotations.containerImage",
+		}))
+
+		obj := replace()
+		Expect(description(obj)).To(Equal("Run registry.example.com/team/operator:1 or registry.example.com/team/sample:1"))
+		Expect(sample(obj)).To(Equal("registry.example.com/team/sample:1"))
+
+		Expect(csv.SetExcludePaths("spec[")).To(HaveOccurred())
+	})
+
+	It("should parse scopes", func() {
+		Expect(ParseScope("known")).To(Equal(ScopeKnown))
+
+		_, err := ParseScope("other")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,199 @@
+package pullspec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/operator-framework/operator-manifest-tools/internal/utils"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+)
+
+const (
+	// SkipImagesAnnotation is the CSV annotation listing the images that must
+	// not be pinned, like must-gather images kept on a floating tag on purpose.
+	// The images are separated by commas or new lines and may be patterns like
+	// quay.io/team/must-gather:*, see path.Match.
+	SkipImagesAnnotation = "pinning.operatorframework.io/skip-images"
+	// SkipContainersAnnotation is the annotation of a CSV deployment pod
+	// template listing the containers whose images must not be pinned,
+	// separated by commas.
+	SkipContainersAnnotation = "pinning.operatorframework.io/skip-containers"
+)
+
+// SkipMarkers holds the images and containers of a CSV that must not be
+// pinned. They are still extracted in detail and kept in the relatedImages.
+// The images of the skipped containers are skipped everywhere, like in the
+// relatedImages or the env vars, so pinning again leaves them alone.
+type SkipMarkers struct {
+	images     []string
+	containers map[Owner]bool
+	// containerImages holds the canonical images of the skipped containers.
+	containerImages map[imagename.ImageName]bool
+}
+
+// newSkipMarkers returns markers skipping nothing.
+func newSkipMarkers() *SkipMarkers {
+	return &SkipMarkers{containers: map[Owner]bool{}, containerImages: map[imagename.ImageName]bool{}}
+}
+
+// splitList splits a list separated by commas or new lines.
+func splitList(list string) []string {
+	items := []string{}
+
+	for _, item := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// SkipMarkers reads the skip annotations of the CSV.
+func (csv *OperatorCSV) SkipMarkers() (*SkipMarkers, error) {
+	index := csv.index()
+
+	if index.markers == nil && index.markersErr == nil {
+		index.markers, index.markersErr = csv.findSkipMarkers()
+	}
+
+	return index.markers, index.markersErr
+}
+
+// findSkipMarkers reads the skip annotations of the CSV, see SkipMarkers.
+func (csv *OperatorCSV) findSkipMarkers() (*SkipMarkers, error) {
+	markers := newSkipMarkers()
+
+	if annotations, err := csvAnnotations.M(csv.data.Object); err == nil {
+		images, _ := annotations[SkipImagesAnnotation].(string)
+
+		for _, image := range splitList(images) {
+			if _, err := path.Match(image, ""); err != nil {
+				return nil, fmt.Errorf("%s: invalid %s pattern %q: %w", csv.path, SkipImagesAnnotation, image, err)
+			}
+
+			markers.images = append(markers.images, image)
+		}
+	}
+
+	deployments, err := csv.deployments()
+
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return markers, nil
+		}
+
+		return nil, err
+	}
+
+	for i := range deployments {
+		deployment, _ := deployments[i].(map[string]interface{})
+		name, _ := deployment["name"].(string)
+		annotations, err := deploymentAnnotations.M(deployment)
+
+		if err != nil {
+			continue
+		}
+
+		containers, _ := annotations[SkipContainersAnnotation].(string)
+
+		for _, container := range splitList(containers) {
+			markers.containers[Owner{Deployment: name, Container: container}] = true
+		}
+	}
+
+	if len(markers.containers) == 0 {
+		return markers, nil
+	}
+
+	containers, err := csv.containerPullSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	initContainers, err := csv.initContainerPullSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ps := range append(containers, initContainers...) {
+		if markers.containers[ps.Owner()] {
+			markers.containerImages[imagename.Parse(ps.Image()).Canonical()] = true
+		}
+	}
+
+	return markers, nil
+}
+
+// merge adds the markers of other to the markers.
+func (markers *SkipMarkers) merge(other *SkipMarkers) {
+	markers.images = append(markers.images, other.images...)
+
+	for owner := range other.containers {
+		markers.containers[owner] = true
+	}
+
+	for image := range other.containerImages {
+		markers.containerImages[image] = true
+	}
+}
+
+// SkipsImage returns true if the image must not be pinned, because it matches
+// a skipped image or it's the image of a skipped container.
+func (markers *SkipMarkers) SkipsImage(image string) bool {
+	if markers == nil {
+		return false
+	}
+
+	name := imagename.Parse(image)
+
+	if markers.containerImages[name.Canonical()] {
+		return true
+	}
+
+	parsed := name.String()
+
+	for _, pattern := range markers.images {
+		for _, name := range []string{image, parsed} {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Skips returns true if the image of the pull spec must not be pinned, either
+// because of its image or because of the container holding it.
+func (markers *SkipMarkers) Skips(ps NamedPullSpec) bool {
+	if markers == nil {
+		return false
+	}
+
+	if owner := ps.Owner(); owner.Container != "" && markers.containers[owner] {
+		return true
+	}
+
+	return markers.SkipsImage(ps.Image())
+}
+
+// withoutSkippedImages returns the replacements of the images that aren't
+// skipped.
+func (markers *SkipMarkers) withoutSkippedImages(replacement map[imagename.ImageName]imagename.ImageName) map[imagename.ImageName]imagename.ImageName {
+	if markers == nil || (len(markers.images) == 0 && len(markers.containerImages) == 0) {
+		return replacement
+	}
+
+	kept := make(map[imagename.ImageName]imagename.ImageName, len(replacement))
+
+	for old, new := range replacement {
+		if !markers.SkipsImage(old.String()) {
+			kept[old] = new
+		}
+	}
+
+	return kept
+}
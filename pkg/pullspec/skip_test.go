@@ -0,0 +1,142 @@
+package pullspec
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Skip markers", func() {
+	const src = `kind: ClusterServiceVersion
+metadata:
+  annotations:
+    pinning.operatorframework.io/skip-images: |
+      quay.io/team/must-gather:*
+      registry.example.com/team/sample:latest
+spec:
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            metadata:
+              annotations:
+                pinning.operatorframework.io/skip-containers: debug
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+                env:
+                - name: RELATED_IMAGE_MUST_GATHER
+                  value: quay.io/team/must-gather:latest
+              - name: debug
+                image: registry.example.com/team/debug:1
+`
+
+	var csv *OperatorCSV
+
+	BeforeEach(func() {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err = NewOperatorCSV("csv.yaml", data, DefaultHeuristic)
+		Expect(err).To(Succeed())
+	})
+
+	It("should read the markers", func() {
+		markers, err := csv.SkipMarkers()
+		Expect(err).To(Succeed())
+
+		Expect(markers.SkipsImage("quay.io/team/must-gather:latest")).To(BeTrue())
+		Expect(markers.SkipsImage("quay.io/team/must-gather@sha256:1111111111111111111111111111111111111111111111111111111111111111")).To(BeFalse())
+		Expect(markers.SkipsImage("registry.example.com/team/sample:latest")).To(BeTrue())
+		Expect(markers.SkipsImage("registry.example.com/team/operator:1")).To(BeFalse())
+	})
+
+	It("should not extract skipped images", func() {
+		images, err := csv.GetPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(images).To(ConsistOf(imagename.Parse("registry.example.com/team/operator:1")))
+	})
+
+	It("should not guess images in the skip annotation", func() {
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+
+		for _, ps := range pullspecs {
+			Expect(ps.Kind()).ToNot(Equal(KindAnnotation))
+		}
+	})
+
+	It("should not replace skipped images", func() {
+		replacement := map[imagename.ImageName]imagename.ImageName{}
+		for _, image := range []string{
+			"registry.example.com/team/operator:1",
+			"registry.example.com/team/debug:1",
+			"quay.io/team/must-gather:latest",
+		} {
+			replacement[*imagename.Parse(image)] = *imagename.Parse(image + "-pinned")
+		}
+
+		Expect(csv.ReplacePullSpecsEverywhere(replacement)).To(Succeed())
+
+		images := map[string]string{}
+		pullspecs, err := csv.NamedPullSpecs()
+		Expect(err).To(Succeed())
+		for _, ps := range pullspecs {
+			images[ps.Location().Path] = ps.Image()
+		}
+
+		containers := "spec.install.spec.deployments[0].spec.template.spec.containers"
+		Expect(images).To(HaveKeyWithValue(containers+"[0].image", "registry.example.com/team/operator:1-pinned"))
+		Expect(images).To(HaveKeyWithValue(containers+"[0].env[0].value", "quay.io/team/must-gather:latest"))
+		Expect(images).To(HaveKeyWithValue(containers+"[1].image", "registry.example.com/team/debug:1"))
+	})
+
+	It("should leave the images of skipped containers alone when pinning again", func() {
+		pin := func() {
+			images, err := csv.GetPullSpecs()
+			Expect(err).To(Succeed())
+
+			replacement := map[imagename.ImageName]imagename.ImageName{}
+			for _, image := range images {
+				if !image.HasDigest() {
+					name := image.String()
+					replacement[*image] = *imagename.Parse(name[:strings.LastIndex(name, ":")] +
+						"@sha256:1111111111111111111111111111111111111111111111111111111111111111")
+				}
+			}
+
+			Expect(csv.ReplacePullSpecsEverywhere(replacement)).To(Succeed())
+			Expect(csv.SetRelatedImages()).To(Succeed())
+		}
+
+		pin()
+		pinned := csv.data.DeepCopy()
+
+		relatedImages, err := csv.relatedImagePullSpecs()
+		Expect(err).To(Succeed())
+		Expect(relatedImages).To(ContainElement(WithTransform(NamedPullSpec.Image, Equal("registry.example.com/team/debug:1"))))
+
+		images, err := csv.GetPullSpecs()
+		Expect(err).To(Succeed())
+		Expect(images).To(ConsistOf(imagename.Parse("registry.example.com/team/operator@sha256:1111111111111111111111111111111111111111111111111111111111111111")))
+
+		pin()
+		Expect(csv.data.Object).To(Equal(pinned.Object))
+	})
+
+	It("should reject invalid patterns", func() {
+		csv.data.SetAnnotations(map[string]string{SkipImagesAnnotation: "quay.io/[team"})
+
+		_, err := csv.SkipMarkers()
+		Expect(err).To(MatchError(ContainSubstring("invalid")))
+	})
+})
@@ -0,0 +1,226 @@
+package pullspec
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// KindSuppressed is a string the heuristic found that matches a suppression,
+// it isn't extracted nor replaced.
+const KindSuppressed PullSpecKind = "suppressed"
+
+// Suppressions are strings the heuristic finds that aren't images, like
+// docs.example.com/guide:v1 in a description. An entry is either:
+//
+//   - a regular expression if it's prefixed with re:, like re:^docs\.example\.com/
+//   - a pattern if it holds one of *?[, like github.com/example/*, see path.Match
+//   - an exact string otherwise
+type Suppressions struct {
+	exact   map[string]bool
+	globs   []string
+	regexes []*regexp.Regexp
+}
+
+// NewSuppressions parses the suppression entries.
+func NewSuppressions(entries ...string) (*Suppressions, error) {
+	suppressions := &Suppressions{exact: map[string]bool{}}
+
+	for _, entry := range entries {
+		switch {
+		case strings.HasPrefix(entry, "re:"):
+			re, err := regexp.Compile(strings.TrimPrefix(entry, "re:"))
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid suppression %q: %w", entry, err)
+			}
+
+			suppressions.regexes = append(suppressions.regexes, re)
+		case strings.ContainsAny(entry, "*?["):
+			if _, err := path.Match(entry, ""); err != nil {
+				return nil, fmt.Errorf("invalid suppression %q: %w", entry, err)
+			}
+
+			suppressions.globs = append(suppressions.globs, entry)
+		default:
+			suppressions.exact[entry] = true
+		}
+	}
+
+	return suppressions, nil
+}
+
+// LoadSuppressions reads the suppression entries of a file, one per line.
+// Empty lines and lines starting with # are ignored.
+func LoadSuppressions(path string) ([]string, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	entries := []string{}
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entries = append(entries, line)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Suppresses returns true if the candidate matches one of the suppressions.
+func (suppressions *Suppressions) Suppresses(candidate string) bool {
+	if suppressions == nil {
+		return false
+	}
+
+	if suppressions.exact[candidate] {
+		return true
+	}
+
+	for _, glob := range suppressions.globs {
+		if matched, _ := path.Match(glob, candidate); matched {
+			return true
+		}
+	}
+
+	for _, re := range suppressions.regexes {
+		if re.MatchString(candidate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetSuppressions sets the strings the heuristic must not report as images.
+func (doc *document) SetSuppressions(suppressions *Suppressions) {
+	doc.suppressions = suppressions
+	doc.invalidate()
+}
+
+// suppress drops the results of the heuristic matching a suppression.
+func (doc *document) suppress(text string, results [][]int) [][]int {
+	if doc.suppressions == nil {
+		return results
+	}
+
+	kept := make([][]int, 0, len(results))
+
+	for _, bounds := range results {
+		if candidate := text[bounds[0]:bounds[1]]; doc.suppressions.Suppresses(candidate) {
+			log.Printf("%s - Pull spec heuristic: %s is suppressed\n", doc.path, candidate)
+			continue
+		}
+
+		kept = append(kept, bounds)
+	}
+
+	return kept
+}
+
+// suppressedPullSpec is a pull spec dropped by a suppression.
+type suppressedPullSpec struct {
+	NamedPullSpec
+}
+
+// Kind returns the kind of the pullspec.
+func (ps *suppressedPullSpec) Kind() PullSpecKind {
+	return KindSuppressed
+}
+
+// suppressedPullSpecs returns the pull specs namedPullSpecs finds only once the
+// suppressions are lifted.
+func (doc *document) suppressedPullSpecs(namedPullSpecs func() ([]NamedPullSpec, error)) ([]NamedPullSpec, error) {
+	suppressions := doc.suppressions
+	suppressed := []NamedPullSpec{}
+
+	if suppressions == nil {
+		return suppressed, nil
+	}
+
+	kept, err := namedPullSpecs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc.SetSuppressions(nil)
+	all, err := namedPullSpecs()
+	doc.SetSuppressions(suppressions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := func(ps NamedPullSpec) string {
+		return ps.Location().Path + "\x00" + ps.Image()
+	}
+
+	found := map[string]bool{}
+
+	for _, ps := range kept {
+		found[key(ps)] = true
+	}
+
+	for _, ps := range all {
+		if ps.Kind() == KindAnnotation && !found[key(ps)] && suppressions.Suppresses(ps.Image()) {
+			suppressed = append(suppressed, &suppressedPullSpec{NamedPullSpec: ps})
+		}
+	}
+
+	return suppressed, nil
+}
+
+// SuppressedPullSpecs returns the strings of the CSV the heuristic found but
+// that match a suppression, as KindSuppressed pull specs.
+func (csv *OperatorCSV) SuppressedPullSpecs() ([]NamedPullSpec, error) {
+	return csv.suppressedPullSpecs(csv.NamedPullSpecs)
+}
+
+// SuppressedPullSpecs returns the strings of the manifest the heuristic found
+// but that match a suppression, as KindSuppressed pull specs.
+func (manifest *Manifest) SuppressedPullSpecs() ([]NamedPullSpec, error) {
+	return manifest.suppressedPullSpecs(manifest.NamedPullSpecs)
+}
+
+// SuppressedPullSpecs returns the strings of the bundle the heuristic found
+// but that match a suppression, as KindSuppressed pull specs.
+func (bundle *Bundle) SuppressedPullSpecs() ([]NamedPullSpec, error) {
+	suppressed := []NamedPullSpec{}
+
+	for _, csv := range bundle.CSVs {
+		pullspecs, err := csv.SuppressedPullSpecs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		suppressed = append(suppressed, pullspecs...)
+	}
+
+	for _, manifest := range bundle.Manifests {
+		pullspecs, err := manifest.SuppressedPullSpecs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		suppressed = append(suppressed, pullspecs...)
+	}
+
+	return suppressed, nil
+}
@@ -0,0 +1,113 @@
+package pullspec
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/operator-manifest-tools/pkg/imagename"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Suppressions", func() {
+	const src = `kind: ClusterServiceVersion
+metadata:
+  annotations:
+    description: See docs.example.com/guide:v1 and github.com/example/tool:v2
+spec:
+  description: Runs registry.example.com/team/agent:1, see docs.example.com/guide:v1.
+  install:
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              containers:
+              - name: manager
+                image: registry.example.com/team/operator:1
+`
+
+	var csv *OperatorCSV
+
+	BeforeEach(func() {
+		data := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err := dec.Decode([]byte(src), nil, data)
+		Expect(err).To(Succeed())
+
+		csv, err = NewOperatorCSV("csv.yaml", data, DefaultHeuristic)
+		Expect(err).To(Succeed())
+	})
+
+	It("should match exact strings, patterns and regular expressions", func() {
+		suppressions, err := NewSuppressions("docs.example.com/guide:v1", "github.com/example/*", `re:^quay\.io/sample/`)
+		Expect(err).To(Succeed())
+
+		Expect(suppressions.Suppresses("docs.example.com/guide:v1")).To(BeTrue())
+		Expect(suppressions.Suppresses("docs.example.com/guide:v2")).To(BeFalse())
+		Expect(suppressions.Suppresses("github.com/example/tool:v2")).To(BeTrue())
+		Expect(suppressions.Suppresses("quay.io/sample/app:1")).To(BeTrue())
+		Expect(suppressions.Suppresses("quay.io/team/app:1")).To(BeFalse())
+	})
+
+	It("should reject invalid entries", func() {
+		_, err := NewSuppressions("re:(")
+		Expect(err).To(MatchError(ContainSubstring(`invalid suppression "re:("`)))
+
+		_, err = NewSuppressions("quay.io/[team")
+		Expect(err).To(MatchError(ContainSubstring("invalid suppression")))
+	})
+
+	It("should load entries from a file", func() {
+		dir, err := os.MkdirTemp("", "suppressions")
+		Expect(err).To(Succeed())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "suppressions")
+		Expect(os.WriteFile(path, []byte("# docs\ndocs.example.com/guide:v1\n\n  github.com/example/*  \n"), 0644)).To(Succeed())
+
+		entries, err := LoadSuppressions(path)
+		Expect(err).To(Succeed())
+		Expect(entries).To(Equal([]string{"docs.example.com/guide:v1", "github.com/example/*"}))
+	})
+
+	Context("with suppressions", func() {
+		BeforeEach(func() {
+			config := &ScanConfig{Suppress: []string{"docs.example.com/guide:v1", "github.com/example/*"}}
+			Expect(config.Apply(csv)).To(Succeed())
+		})
+
+		It("should not extract suppressed strings", func() {
+			images, err := csv.GetPullSpecs()
+			Expect(err).To(Succeed())
+			Expect(images).To(ConsistOf(imagename.Parse("registry.example.com/team/operator:1")))
+		})
+
+		It("should report suppressed strings", func() {
+			suppressed, err := csv.SuppressedPullSpecs()
+			Expect(err).To(Succeed())
+
+			strs := []string{}
+			for _, ps := range suppressed {
+				Expect(ps.Kind()).To(Equal(KindSuppressed))
+				strs = append(strs, ps.Image())
+			}
+
+			Expect(strs).To(ConsistOf("docs.example.com/guide:v1", "github.com/example/tool:v2"))
+			Expect(suppressed[0].Location().Path).To(HavePrefix("metadata.annotations"))
+		})
+
+		It("should not replace suppressed strings", func() {
+			Expect(csv.ReplacePullSpecsEverywhere(map[imagename.ImageName]imagename.ImageName{
+				*imagename.Parse("docs.example.com/guide:v1"):         *imagename.Parse("docs.example.com/guide:v2"),
+				*imagename.Parse("registry.example.com/team/agent:1"): *imagename.Parse("registry.example.com/team/agent:2"),
+			})).To(Succeed())
+
+			Expect(csv.data.Object["spec"].(map[string]interface{})["description"]).
+				To(Equal("Runs registry.example.com/team/agent:2, see docs.example.com/guide:v1."))
+		})
+	})
+})